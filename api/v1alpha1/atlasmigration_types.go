@@ -58,6 +58,16 @@ type (
 		ObservedHash string `json:"observed_hash"`
 		// LastApplied is the unix timestamp of the most recent successful versioned migration.
 		LastApplied int64 `json:"lastApplied"`
+		// LockedSum is the atlas.sum of the last applied directory, pinned by the generate-and-lock integrity mode.
+		// +optional
+		LockedSum string `json:"lockedSum,omitempty"`
+		// LockTimeouts counts the consecutive attempts that failed on a lock timeout.
+		// It is reset once the migrations are applied.
+		// +optional
+		LockTimeouts int32 `json:"lockTimeouts,omitempty"`
+		// Tenants is the state of the tenant schemas, ordered by name.
+		// +optional
+		Tenants []SetTargetStatus `json:"tenants,omitempty"`
 	}
 	// AtlasMigrationSpec defines the desired state of AtlasMigration
 	AtlasMigrationSpec struct {
@@ -85,11 +95,55 @@ type (
 		RevisionsSchema string `json:"revisionsSchema,omitempty"`
 		// BaselineVersion defines the baseline version of the database on the first migration.
 		Baseline string `json:"baseline,omitempty"`
+		// Adopt enables adopting an existing database that has no revisions table.
+		// The operator inspects the database and uses the migration version whose schema
+		// matches it as the baseline. If several versions match, e.g. because data-only
+		// migrations do not change the schema, the adoption fails instead of guessing
+		// which data was applied, and the baseline must be set explicitly.
+		// It is ignored if baseline is set.
+		// +optional
+		Adopt bool `json:"adopt,omitempty"`
+		// HandoverFrom references the AtlasSchema that currently manages the target database.
+		// The operator verifies that the database is in sync with the AtlasSchema and matches
+		// the baseline version, which defaults to the first migration version. Then, it releases
+		// the AtlasSchema and creates the revisions table with that baseline.
+		// +optional
+		HandoverFrom *corev1.LocalObjectReference `json:"handoverFrom,omitempty"`
 		// ExecOrder controls how Atlas computes and executes pending migration files to the database.
 		// +kubebuilder:default=linear
 		ExecOrder MigrateExecOrder `json:"execOrder,omitempty"`
 		// ProtectedFlows defines the protected flows of a deployment.
 		ProtectedFlows *ProtectFlows `json:"protectedFlows,omitempty"`
+		// Policy defines the policies to apply when managing the migration lifecycle.
+		// +optional
+		Policy *MigrationPolicy `json:"policy,omitempty"`
+		// Tests defines the migration tests to run against the dev database before
+		// pending migrations are applied. A failing test stops the apply.
+		// +optional
+		Tests *Tests `json:"tests,omitempty"`
+		// Timeouts defines the lock and statement timeouts of the connections to the target database.
+		// +optional
+		Timeouts *Timeouts `json:"timeouts,omitempty"`
+		// Paused stops applying the migration directory to the target database, e.g. while
+		// an AtlasMigrationOperation reverts migrations that are still in the directory.
+		// +optional
+		Paused bool `json:"paused,omitempty"`
+		// Tenants applies the migration directory to every tenant schema of the target database,
+		// each one by a child AtlasMigration bound to the schema of the tenant.
+		// +optional
+		Tenants *Tenants `json:"tenants,omitempty"`
+	}
+	// MigrationPolicy defines the policies to apply when managing the migration lifecycle.
+	MigrationPolicy struct {
+		// RejectEdited refuses to apply the migration directory if files that
+		// were already applied to the target database have been edited.
+		// +optional
+		RejectEdited bool `json:"rejectEdited,omitempty"`
+		// OnFailure defines what to do when a migration fails midway. If set to rollback,
+		// the operator reverts the database to the version recorded before the run.
+		// It requires a local migration directory with `protectedFlows.migrateDown.allow`.
+		// +optional
+		OnFailure MigrationFailurePolicy `json:"onFailure,omitempty"`
 	}
 	CloudV0 struct {
 		URL       string    `json:"url,omitempty"`
@@ -109,6 +163,13 @@ type (
 		Remote Remote `json:"remote,omitempty"`
 		// Local defines the local migration directory.
 		Local map[string]string `json:"local,omitempty"`
+		// Integrity controls how the integrity of a local or ConfigMap directory is verified.
+		// require expects the directory to contain a valid atlas.sum file, generate computes it
+		// before running Atlas, and generate-and-lock also pins the checksums of the applied
+		// files in the status and rejects later edits to them.
+		// +kubebuilder:default=require
+		// +optional
+		Integrity DirIntegrity `json:"integrity,omitempty"`
 	}
 	// Remote defines the Atlas Cloud directory migration.
 	Remote struct {
@@ -128,6 +189,25 @@ type (
 		// +kubebuilder:default=false
 		AutoApprove bool `json:"autoApprove,omitempty"`
 	}
+	// MigrationFailurePolicy defines the action to take when a migration fails.
+	// +kubebuilder:validation:Enum=fail;rollback
+	MigrationFailurePolicy string
+	// DirIntegrity defines how the integrity of a migration directory is verified.
+	// +kubebuilder:validation:Enum=require;generate;generate-and-lock
+	DirIntegrity string
+)
+
+// MigrationFailurePolicy values.
+const (
+	MigrationFailureFail     MigrationFailurePolicy = "fail"
+	MigrationFailureRollback MigrationFailurePolicy = "rollback"
+)
+
+// DirIntegrity values.
+const (
+	DirIntegrityRequire         DirIntegrity = "require"
+	DirIntegrityGenerate        DirIntegrity = "generate"
+	DirIntegrityGenerateAndLock DirIntegrity = "generate-and-lock"
 )
 
 // ExecOrder controls how Atlas computes and executes pending migration files to the database.
@@ -179,3 +259,10 @@ func (m *AtlasMigration) SetNotReady(reason, message string) {
 		Message: message,
 	})
 }
+
+// SetTenants sets the state of the tenants, and the ready condition
+// to true if all the tenants are ready.
+func (m *AtlasMigration) SetTenants(tenants []SetTargetStatus) {
+	m.Status.Tenants = tenants
+	meta.SetStatusCondition(&m.Status.Conditions, setCondition(countReady(tenants), len(tenants), "tenants"))
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type (
+	//+kubebuilder:object:root=true
+	//
+	// AtlasMigrationOperationList contains a list of AtlasMigrationOperation
+	AtlasMigrationOperationList struct {
+		metav1.TypeMeta `json:",inline"`
+		metav1.ListMeta `json:"metadata,omitempty"`
+
+		Items []AtlasMigrationOperation `json:"items"`
+	}
+	//+kubebuilder:object:root=true
+	//+kubebuilder:subresource:status
+	//
+	// AtlasMigrationOperation is the Schema for the atlasmigrationoperations API.
+	// It runs a single administrative operation against the target database of an AtlasMigration.
+	// +kubebuilder:printcolumn:name="Migration",type=string,JSONPath=`.spec.migrationRef.name`
+	// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+	// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+	// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+	AtlasMigrationOperation struct {
+		metav1.TypeMeta   `json:",inline"`
+		metav1.ObjectMeta `json:"metadata,omitempty"`
+
+		Spec   AtlasMigrationOperationSpec   `json:"spec,omitempty"`
+		Status AtlasMigrationOperationStatus `json:"status,omitempty"`
+	}
+	// AtlasMigrationOperationSpec defines the operation to run.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+	// +kubebuilder:validation:XValidation:rule="!(self.type in ['set', 'resolve']) || has(self.version)",message="version is required for set and resolve operations"
+	AtlasMigrationOperationSpec struct {
+		// MigrationRef references the AtlasMigration whose target database,
+		// dev database and migration directory are used by the operation.
+		MigrationRef corev1.LocalObjectReference `json:"migrationRef"`
+		// Type of the operation to run.
+		Type MigrationOperationType `json:"type"`
+		// Version is the target version of the set and resolve operations.
+		// +optional
+		Version string `json:"version,omitempty"`
+		// Amount is the number of migrations to revert by the down operation.
+		// +kubebuilder:default=1
+		// +optional
+		Amount uint64 `json:"amount,omitempty"`
+	}
+	// AtlasMigrationOperationStatus defines the observed state of AtlasMigrationOperation
+	AtlasMigrationOperationStatus struct {
+		// Conditions represent the latest available observations of an object's state.
+		Conditions []metav1.Condition `json:"conditions,omitempty"`
+		// Result describes the outcome of the operation.
+		// +optional
+		Result string `json:"result,omitempty"`
+		// CurrentVersion is the version of the database after the operation.
+		// +optional
+		CurrentVersion string `json:"currentVersion,omitempty"`
+		// CompletedAt is the unix timestamp of the operation completion.
+		// +optional
+		CompletedAt int64 `json:"completedAt,omitempty"`
+	}
+	// MigrationOperationType defines the type of the migration operation.
+	// +kubebuilder:validation:Enum=set;resolve;rehash;down
+	MigrationOperationType string
+)
+
+// MigrationOperationType values.
+const (
+	// MigrationOperationSet sets the current version of the revisions table.
+	MigrationOperationSet MigrationOperationType = "set"
+	// MigrationOperationResolve marks a failed version as applied.
+	MigrationOperationResolve MigrationOperationType = "resolve"
+	// MigrationOperationRehash re-computes the atlas.sum of the migration directory.
+	MigrationOperationRehash MigrationOperationType = "rehash"
+	// MigrationOperationDown reverts the given amount of applied migrations.
+	MigrationOperationDown MigrationOperationType = "down"
+)
+
+func init() {
+	SchemeBuilder.Register(&AtlasMigrationOperation{}, &AtlasMigrationOperationList{})
+}
+
+// NamespacedName returns the namespaced name of the object.
+func (o *AtlasMigrationOperation) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      o.Name,
+		Namespace: o.Namespace,
+	}
+}
+
+// MigrationName returns the namespaced name of the referenced AtlasMigration.
+func (o *AtlasMigrationOperation) MigrationName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      o.Spec.MigrationRef.Name,
+		Namespace: o.Namespace,
+	}
+}
+
+// IsReady returns true if the ready condition is true.
+func (o *AtlasMigrationOperation) IsReady() bool {
+	return meta.IsStatusConditionTrue(o.Status.Conditions, readyCond)
+}
+
+// SetReady sets the ready condition to true.
+func (o *AtlasMigrationOperation) SetReady(status AtlasMigrationOperationStatus) {
+	o.Status = status
+	meta.SetStatusCondition(&o.Status.Conditions, metav1.Condition{
+		Type:    readyCond,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Completed",
+		Message: status.Result,
+	})
+}
+
+// SetNotReady sets the ready condition to false.
+func (o *AtlasMigrationOperation) SetNotReady(reason, message string) {
+	meta.SetStatusCondition(&o.Status.Conditions, metav1.Condition{
+		Type:    readyCond,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+}
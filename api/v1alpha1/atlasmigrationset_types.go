@@ -0,0 +1,110 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type (
+	//+kubebuilder:object:root=true
+	//
+	// AtlasMigrationSetList contains a list of AtlasMigrationSet
+	AtlasMigrationSetList struct {
+		metav1.TypeMeta `json:",inline"`
+		metav1.ListMeta `json:"metadata,omitempty"`
+
+		Items []AtlasMigrationSet `json:"items"`
+	}
+	//+kubebuilder:object:root=true
+	//+kubebuilder:subresource:status
+	//
+	// AtlasMigrationSet is the Schema for the atlasmigrationsets API.
+	// It applies one migration directory to many databases, by creating an AtlasMigration for each target database.
+	// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+	// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+	// +kubebuilder:printcolumn:name="Ready Targets",type=integer,JSONPath=`.status.readyTargets`
+	// +kubebuilder:printcolumn:name="Total",type=integer,JSONPath=`.status.totalTargets`
+	AtlasMigrationSet struct {
+		metav1.TypeMeta   `json:",inline"`
+		metav1.ObjectMeta `json:"metadata,omitempty"`
+
+		Spec   AtlasMigrationSetSpec   `json:"spec,omitempty"`
+		Status AtlasMigrationSetStatus `json:"status,omitempty"`
+	}
+	// AtlasMigrationSetSpec defines the desired state of AtlasMigrationSet
+	AtlasMigrationSetSpec struct {
+		// Generator generates the target databases of the set.
+		Generator TargetGenerator `json:"generator"`
+		// Template is the template of the AtlasMigration resources created for the targets.
+		// The target database of the template is replaced by the generated one.
+		Template AtlasMigrationTemplate `json:"template"`
+	}
+	// AtlasMigrationTemplate is the template of the AtlasMigration resources of a set.
+	AtlasMigrationTemplate struct {
+		// +optional
+		Metadata TemplateMeta       `json:"metadata,omitempty"`
+		Spec     AtlasMigrationSpec `json:"spec"`
+	}
+	// AtlasMigrationSetStatus defines the observed state of AtlasMigrationSet
+	AtlasMigrationSetStatus struct {
+		// Conditions represent the latest available observations of an object's state.
+		Conditions []metav1.Condition `json:"conditions,omitempty"`
+		// Targets is the state of the target databases, ordered by name.
+		// +optional
+		Targets []SetTargetStatus `json:"targets,omitempty"`
+		// ReadyTargets is the number of ready targets.
+		ReadyTargets int `json:"readyTargets"`
+		// TotalTargets is the number of targets.
+		TotalTargets int `json:"totalTargets"`
+	}
+)
+
+func init() {
+	SchemeBuilder.Register(&AtlasMigrationSet{}, &AtlasMigrationSetList{})
+}
+
+// NamespacedName returns the namespaced name of the object.
+func (s *AtlasMigrationSet) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      s.Name,
+		Namespace: s.Namespace,
+	}
+}
+
+// IsReady returns true if the ready condition is true.
+func (s *AtlasMigrationSet) IsReady() bool {
+	return meta.IsStatusConditionTrue(s.Status.Conditions, readyCond)
+}
+
+// SetTargets sets the state of the targets, and the ready condition to true
+// if all the targets are ready.
+func (s *AtlasMigrationSet) SetTargets(targets []SetTargetStatus) {
+	s.Status.Targets = targets
+	s.Status.ReadyTargets, s.Status.TotalTargets = countReady(targets), len(targets)
+	meta.SetStatusCondition(&s.Status.Conditions, setCondition(s.Status.ReadyTargets, s.Status.TotalTargets, "targets"))
+}
+
+// SetNotReady sets the ready condition to false.
+func (s *AtlasMigrationSet) SetNotReady(reason, message string) {
+	meta.SetStatusCondition(&s.Status.Conditions, metav1.Condition{
+		Type:    readyCond,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+}
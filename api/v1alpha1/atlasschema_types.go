@@ -56,8 +56,14 @@ type (
 	AtlasSchemaStatus struct {
 		// Conditions represent the latest available observations of an object's state.
 		Conditions []metav1.Condition `json:"conditions,omitempty"`
-		// ObservedHash is the hash of the most recently applied schema.
+		// ObservedHash is the hash of the inputs of the most recently applied schema:
+		// the desired schema, target and dev URLs, schemas, exclude, policy, txMode and cloud settings.
 		ObservedHash string `json:"observed_hash"`
+		// ObservedHashes is the breakdown of ObservedHash by input.
+		// It is used to report which of the inputs was changed.
+		// Passwords and tokens are not part of the breakdown.
+		// +optional
+		ObservedHashes map[string]string `json:"observedHashes,omitempty"`
 		// LastApplied is the unix timestamp of the most recent successful schema apply operation.
 		LastApplied int64 `json:"last_applied"`
 		// PlanURL is the URL of the schema plan to apply.
@@ -66,12 +72,42 @@ type (
 		// PlanLink is the link to the schema plan on the Atlas Cloud.
 		// +optional
 		PlanLink string `json:"planLink"`
+		// Diagnostics lists the diagnostics reported by the linting policy
+		// on the most recent run.
+		// +optional
+		Diagnostics []LintDiagnostic `json:"diagnostics,omitempty"`
+		// Impact lists the statistics of the tables touched by the most recent planned changes,
+		// if the impact policy is set.
+		// +optional
+		Impact []TableImpact `json:"impact,omitempty"`
+		// AcknowledgedDestructive is the hash of the destructive changes that were
+		// acknowledged and applied on the first run of the schema, if any.
+		// +optional
+		AcknowledgedDestructive string `json:"acknowledgedDestructive,omitempty"`
+		// LastInspected is the unix timestamp of the most recent inspection, in inspect mode.
+		// +optional
+		LastInspected int64 `json:"lastInspected,omitempty"`
+		// Rollout reports the phases of the most recent expand/contract rollout.
+		// +optional
+		Rollout *RolloutStatus `json:"rollout,omitempty"`
+		// OnlineSchemaChange reports the most recent statement run by the online schema change tool.
+		// +optional
+		OnlineSchemaChange *OnlineSchemaChangeStatus `json:"onlineSchemaChange,omitempty"`
+		// LockTimeouts counts the consecutive attempts that failed on a lock timeout.
+		// It is reset once the schema is applied.
+		// +optional
+		LockTimeouts int32 `json:"lockTimeouts,omitempty"`
+		// Tenants is the state of the tenant schemas, ordered by name.
+		// +optional
+		Tenants []SetTargetStatus `json:"tenants,omitempty"`
 	}
 	// AtlasSchemaSpec defines the desired state of AtlasSchema
 	AtlasSchemaSpec struct {
 		TargetSpec `json:",inline"`
 		// Desired Schema of the target.
 		Schema Schema `json:"schema,omitempty"`
+		// EnvName sets the environment name used for reporting runs to Atlas Cloud.
+		EnvName string `json:"envName,omitempty"`
 		// Cloud defines the Atlas Cloud configuration.
 		Cloud Cloud `json:"cloud,omitempty"`
 		// +optional
@@ -89,6 +125,11 @@ type (
 		DevURLFrom Secret `json:"devURLFrom,omitempty"`
 		// Exclude a list of glob patterns used to filter existing resources being taken into account.
 		Exclude []string `json:"exclude,omitempty"`
+		// Include a list of glob patterns used to select the existing resources being taken into account.
+		// Resources that share a target database must own disjoint sets of objects,
+		// defined by their include and exclude patterns.
+		// +optional
+		Include []string `json:"include,omitempty"`
 		// TxMode defines the transaction mode to use when applying the schema.
 		// +kubebuilder:default=file
 		TxMode TransactionMode `json:"txMode,omitempty"`
@@ -96,6 +137,162 @@ type (
 		Policy *Policy `json:"policy,omitempty"`
 		// The names of the schemas (named databases) on the target database to be managed.
 		Schemas []string `json:"schemas,omitempty"`
+		// Mode defines how the resource is reconciled. In "apply" mode, the desired schema is
+		// applied to the target database. In "inspect" mode, the target database is only inspected,
+		// and its schema is exported to a ConfigMap.
+		// +kubebuilder:default=apply
+		// +optional
+		Mode SchemaMode `json:"mode,omitempty"`
+		// Inspect configures the inspect mode.
+		// +optional
+		Inspect *InspectSpec `json:"inspect,omitempty"`
+		// Docs enables generating the documentation of the schema after each successful
+		// apply or inspection. The documentation is written to a ConfigMap.
+		// +optional
+		Docs *DocsSpec `json:"docs,omitempty"`
+		// Tests defines the schema tests to run against the dev database before the
+		// planned changes are applied. A failing test stops the apply.
+		// +optional
+		Tests *Tests `json:"tests,omitempty"`
+		// Timeouts defines the lock and statement timeouts of the connections to the target database.
+		// +optional
+		Timeouts *Timeouts `json:"timeouts,omitempty"`
+		// Rollout defines how the planned changes are rolled out to the target database.
+		// +optional
+		Rollout *Rollout `json:"rollout,omitempty"`
+		// OnlineSchemaChange runs the qualifying ALTER TABLE statements of MySQL and MariaDB
+		// targets with an online schema change tool, in a Job, instead of applying them directly.
+		// +optional
+		OnlineSchemaChange *OnlineSchemaChange `json:"onlineSchemaChange,omitempty"`
+		// Output defines the artifacts generated from the changes of the desired schema.
+		// +optional
+		Output *Output `json:"output,omitempty"`
+		// Tenants applies the desired schema to every tenant schema of the target database,
+		// each one by a child AtlasSchema bound to the schema of the tenant.
+		// +optional
+		Tenants *Tenants `json:"tenants,omitempty"`
+	}
+	// OnlineSchemaChange configures the online schema changes of large tables.
+	// An ALTER TABLE statement qualifies if its table matches the table patterns and
+	// holds at least the given number of rows. The other planned changes are applied
+	// directly, before the online schema changes are run one at a time.
+	OnlineSchemaChange struct {
+		// Tool is the online schema change tool.
+		// +kubebuilder:default=gh-ost
+		// +optional
+		Tool OnlineSchemaChangeTool `json:"tool,omitempty"`
+		// Image is the container image of the tool.
+		Image string `json:"image"`
+		// Args are extra arguments passed to the tool, e.g. "--allow-on-master" for gh-ost.
+		// +optional
+		Args []string `json:"args,omitempty"`
+		// Tables is a list of glob patterns of the tables, qualified by their schema
+		// (e.g. "app.orders"), that qualify for online schema changes. Defaults to all the tables.
+		// +optional
+		Tables []string `json:"tables,omitempty"`
+		// MinRows is the minimum estimated number of rows of a table to qualify for online schema changes.
+		// +optional
+		MinRows int64 `json:"minRows,omitempty"`
+	}
+	// OnlineSchemaChangeStatus reports the statement run by the online schema change tool.
+	OnlineSchemaChangeStatus struct {
+		// Job is the name of the Job running the statement.
+		Job string `json:"job"`
+		// Table is the qualified name of the altered table.
+		Table string `json:"table"`
+		// Statement is the ALTER TABLE statement.
+		Statement string `json:"statement"`
+		// Pending lists the qualifying statements waiting for their turn.
+		// +optional
+		Pending []string `json:"pending,omitempty"`
+	}
+	// Rollout defines how the planned changes are rolled out to the target database.
+	Rollout struct {
+		// Strategy is the rollout strategy. In "direct" rollouts, all the planned changes are
+		// applied at once. In "expand-contract" rollouts, the additive changes are applied
+		// immediately, and the destructive (contracting) changes are held back until the
+		// contract gate opens.
+		// +kubebuilder:default=direct
+		// +optional
+		Strategy RolloutStrategy `json:"strategy,omitempty"`
+		// Contract defines the gate that must open before the contracting changes are applied.
+		// +optional
+		Contract *ContractGate `json:"contract,omitempty"`
+	}
+	// ContractGate defines the conditions to apply the contracting changes of an
+	// expand/contract rollout. If several conditions are set, all of them must be met.
+	ContractGate struct {
+		// Annotation waits for the resource to be annotated with atlasgo.io/approve-contract,
+		// set to the hash of the held-back changes reported in the status.
+		// +optional
+		Annotation bool `json:"annotation,omitempty"`
+		// DeploymentRef waits for the referenced Deployment, in the namespace of the resource,
+		// to complete a rollout that started after the expand phase.
+		// +optional
+		DeploymentRef *corev1.LocalObjectReference `json:"deploymentRef,omitempty"`
+		// Delay waits for the given duration after the expand phase.
+		// +optional
+		Delay *metav1.Duration `json:"delay,omitempty"`
+	}
+	// RolloutStatus reports the phases of an expand/contract rollout.
+	RolloutStatus struct {
+		// Phase is the phase the rollout reached.
+		Phase RolloutPhase `json:"phase"`
+		// ContractHash is the hash of the contracting changes.
+		ContractHash string `json:"contractHash,omitempty"`
+		// Expand lists the statements applied in the expand phase.
+		// +optional
+		Expand []string `json:"expand,omitempty"`
+		// Contract lists the statements held back for the contract phase.
+		// +optional
+		Contract []string `json:"contract,omitempty"`
+		// ExpandedAt is the time the expand phase was applied.
+		ExpandedAt metav1.Time `json:"expandedAt"`
+		// ContractedAt is the time the contract phase was applied.
+		// +optional
+		ContractedAt *metav1.Time `json:"contractedAt,omitempty"`
+		// DeploymentGeneration is the generation of the referenced
+		// Deployment that must complete its rollout.
+		// +optional
+		DeploymentGeneration int64 `json:"deploymentGeneration,omitempty"`
+	}
+	// DocsSpec configures the generated documentation of the schema.
+	DocsSpec struct {
+		// ConfigMapName is the name of the ConfigMap the documentation is written to.
+		// Defaults to the name of the resource, suffixed with "-docs".
+		// +optional
+		ConfigMapName string `json:"configMapName,omitempty"`
+	}
+	// Output defines the artifacts generated from the changes of the desired schema.
+	Output struct {
+		// Migrations generates a versioned migration file each time the desired schema
+		// changes. The migration directory is written to a ConfigMap that can be used
+		// as the migration directory of an AtlasMigration resource.
+		// +optional
+		Migrations *MigrationsOutput `json:"migrations,omitempty"`
+	}
+	// MigrationsOutput configures the generated versioned migration files.
+	MigrationsOutput struct {
+		// ConfigMapName is the name of the ConfigMap the migration directory is written to.
+		// Defaults to the name of the resource, suffixed with "-migrations".
+		// +optional
+		ConfigMapName string `json:"configMapName,omitempty"`
+		// Name is the name of the generated migration files, following their version.
+		// Defaults to "schema".
+		// +kubebuilder:default=schema
+		// +optional
+		Name string `json:"name,omitempty"`
+	}
+	// InspectSpec configures how the inspected schema is exported.
+	InspectSpec struct {
+		// ConfigMapName is the name of the ConfigMap the inspected schema is written to.
+		// Defaults to the name of the resource, suffixed with "-schema".
+		// +optional
+		ConfigMapName string `json:"configMapName,omitempty"`
+		// Interval is the interval between two inspections of the target database.
+		// +kubebuilder:default="1h"
+		// +optional
+		Interval metav1.Duration `json:"interval,omitempty"`
 	}
 	// Schema defines the desired state of the target database schema in plain SQL or HCL.
 	Schema struct {
@@ -109,10 +306,47 @@ type (
 	Policy struct {
 		Lint *Lint `json:"lint,omitempty"`
 		Diff *Diff `json:"diff,omitempty"`
+		// Impact defines thresholds on the estimated impact of the planned changes.
+		Impact *Impact `json:"impact,omitempty"`
+	}
+	// Impact defines the thresholds checked against the live statistics
+	// of the tables touched by the planned changes, before they are applied.
+	Impact struct {
+		// MaxRowsRewritten is the maximum estimated number of rows,
+		// summed over the tables touched by the planned changes.
+		// +optional
+		MaxRowsRewritten int64 `json:"maxRowsRewritten,omitempty"`
+		// MaxTableBytes is the maximum estimated size, in bytes,
+		// of a single table touched by the planned changes.
+		// +optional
+		MaxTableBytes int64 `json:"maxTableBytes,omitempty"`
+		// OnExceed defines what happens when a threshold is exceeded.
+		// "block" stops the changes from being applied, "review" requires their
+		// approval on Atlas Cloud, and is only supported for resources connected
+		// to a schema repository.
+		// +kubebuilder:default=block
+		OnExceed ImpactAction `json:"onExceed,omitempty"`
+	}
+	// TableImpact holds the live statistics of a table touched by the planned changes.
+	TableImpact struct {
+		// Table is the qualified name of the table.
+		Table string `json:"table"`
+		// Rows is the estimated number of rows in the table.
+		Rows int64 `json:"rows"`
+		// Bytes is the estimated size of the table, including its indexes.
+		Bytes int64 `json:"bytes"`
 	}
 	// Lint defines the linting policies to apply before applying the schema.
 	Lint struct {
 		Destructive *CheckConfig `json:"destructive,omitempty"`
+		// DataDepend checks for changes that might fail depending on the existing data.
+		DataDepend *CheckConfig `json:"data_depend,omitempty"`
+		// Incompatible checks for backward-incompatible changes, such as renaming columns.
+		Incompatible *CheckConfig `json:"incompatible,omitempty"`
+		// Naming checks that the names of the created resources match a pattern.
+		Naming *NamingCheck `json:"naming,omitempty"`
+		// ConcurrentIndex checks that indexes are created and dropped concurrently (PostgreSQL only).
+		ConcurrentIndex *CheckConfig `json:"concurrent_index,omitempty"`
 		// Review defines the review policy to apply after linting the schema changes.
 		// +kubebuilder:default=ERROR
 		Review LintReview `json:"review,omitempty"`
@@ -121,6 +355,25 @@ type (
 	CheckConfig struct {
 		Error bool `json:"error,omitempty"`
 	}
+	// NamingCheck defines the configuration of the naming check.
+	NamingCheck struct {
+		CheckConfig `json:",inline"`
+		// Match is the regular expression the resource names must match.
+		Match string `json:"match,omitempty"`
+		// Message is the message to report when a name does not match.
+		// +optional
+		Message string `json:"message,omitempty"`
+	}
+	// LintDiagnostic is a diagnostic reported by the linting policy.
+	LintDiagnostic struct {
+		// Code is the code of the diagnostic, e.g. DS102.
+		Code string `json:"code"`
+		// Text is the description of the diagnostic.
+		Text string `json:"text"`
+		// Error is true if the diagnostic failed the linting policy.
+		// +optional
+		Error bool `json:"error,omitempty"`
+	}
 	// Diff defines the diff policies to apply when planning schema changes.
 	Diff struct {
 		ConcurrentIndex *ConcurrentIndex `json:"concurrent_index,omitempty"`
@@ -171,8 +424,75 @@ type (
 	// LintReview defines the review policies to apply after linting the schema.
 	// +kubebuilder:validation:Enum=ALWAYS;WARNING;ERROR
 	LintReview string
+	// SchemaMode defines how an AtlasSchema is reconciled.
+	// +kubebuilder:validation:Enum=apply;inspect
+	SchemaMode string
+	// ImpactAction defines the action to take when an impact threshold is exceeded.
+	// +kubebuilder:validation:Enum=block;review
+	ImpactAction string
+	// RolloutStrategy defines how the planned changes are rolled out.
+	// +kubebuilder:validation:Enum=direct;expand-contract
+	RolloutStrategy string
+	// RolloutPhase is the phase of an expand/contract rollout.
+	RolloutPhase string
+	// OnlineSchemaChangeTool is the tool running the online schema changes.
+	// +kubebuilder:validation:Enum=gh-ost;pt-osc
+	OnlineSchemaChangeTool string
+)
+
+// OnlineSchemaChangeTool values.
+const (
+	OnlineSchemaChangeGhost OnlineSchemaChangeTool = "gh-ost"
+	OnlineSchemaChangePtOSC OnlineSchemaChangeTool = "pt-osc"
 )
 
+// RolloutStrategy values.
+const (
+	RolloutStrategyDirect         RolloutStrategy = "direct"
+	RolloutStrategyExpandContract RolloutStrategy = "expand-contract"
+)
+
+// RolloutPhase values.
+const (
+	RolloutPhaseExpanded   RolloutPhase = "Expanded"
+	RolloutPhaseContracted RolloutPhase = "Contracted"
+)
+
+// AnnotationApproveContract is the annotation used to open the contract gate of an
+// expand/contract rollout. Its value is the hash of the held-back changes.
+const AnnotationApproveContract = "atlasgo.io/approve-contract"
+
+// AnnotationReleasedTo is set on an AtlasSchema that handed over its target database to an
+// AtlasMigration. Its value is the name of the AtlasMigration. Released resources are not reconciled.
+const AnnotationReleasedTo = "atlasgo.io/released-to"
+
+// LabelMigrationsOf is set on the ConfigMap holding the migration directory generated by an
+// AtlasSchema. Its value is the name of the AtlasSchema. The ConfigMap is not owned by the
+// AtlasSchema, so it outlives it, e.g. after the database is handed over to an AtlasMigration.
+const LabelMigrationsOf = "atlasgo.io/migrations-of"
+
+// SchemaMode values.
+const (
+	SchemaModeApply   SchemaMode = "apply"
+	SchemaModeInspect SchemaMode = "inspect"
+)
+
+// Annotations set on the ConfigMap the inspected schema is exported to.
+const (
+	AnnotationSchemaHash  = "atlasgo.io/schema-hash"
+	AnnotationInspectedAt = "atlasgo.io/inspected-at"
+)
+
+// ImpactAction values.
+const (
+	ImpactActionBlock  ImpactAction = "block"
+	ImpactActionReview ImpactAction = "review"
+)
+
+// AnnotationAckDestructive is the annotation used to acknowledge the destructive changes
+// reported on the first run of an AtlasSchema. Its value is the hash of the reported changes.
+const AnnotationAckDestructive = "atlasgo.io/acknowledge-destructive"
+
 // LintReview values.
 const (
 	LintReviewAlways  LintReview = "ALWAYS"
@@ -223,6 +543,45 @@ func (sc *AtlasSchema) SetReady(status AtlasSchemaStatus, report any) {
 	})
 }
 
+// SetInspected sets the Ready condition to true after
+// the target database was inspected, in inspect mode.
+func (sc *AtlasSchema) SetInspected(status AtlasSchemaStatus, msg string) {
+	sc.Status = status
+	meta.SetStatusCondition(&sc.Status.Conditions, metav1.Condition{
+		Type:    readyCond,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Inspected",
+		Message: msg,
+	})
+}
+
+// InspectConfigMapName returns the name of the ConfigMap
+// the inspected schema is written to, in inspect mode.
+func (sc *AtlasSchema) InspectConfigMapName() string {
+	if i := sc.Spec.Inspect; i != nil && i.ConfigMapName != "" {
+		return i.ConfigMapName
+	}
+	return sc.Name + "-schema"
+}
+
+// DocsConfigMapName returns the name of the ConfigMap
+// the documentation of the schema is written to.
+func (sc *AtlasSchema) DocsConfigMapName() string {
+	if d := sc.Spec.Docs; d != nil && d.ConfigMapName != "" {
+		return d.ConfigMapName
+	}
+	return sc.Name + "-docs"
+}
+
+// MigrationsConfigMapName returns the name of the ConfigMap
+// the generated migration directory is written to.
+func (sc *AtlasSchema) MigrationsConfigMapName() string {
+	if o := sc.Spec.Output; o != nil && o.Migrations != nil && o.Migrations.ConfigMapName != "" {
+		return o.Migrations.ConfigMapName
+	}
+	return sc.Name + "-migrations"
+}
+
 // SetNotReady sets the Ready condition to false
 // with the given reason and message.
 func (sc *AtlasSchema) SetNotReady(reason, msg string) {
@@ -272,3 +631,10 @@ func (s Schema) DesiredState(ctx context.Context, r client.Reader, ns string) (*
 	}
 	return nil, nil, fmt.Errorf("no desired state specified")
 }
+
+// SetTenants sets the state of the tenants, and the ready condition
+// to true if all the tenants are ready.
+func (sc *AtlasSchema) SetTenants(tenants []SetTargetStatus) {
+	sc.Status.Tenants = tenants
+	meta.SetStatusCondition(&sc.Status.Conditions, setCondition(countReady(tenants), len(tenants), "tenants"))
+}
@@ -0,0 +1,184 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type (
+	//+kubebuilder:object:root=true
+	//
+	// AtlasSchemaComparisonList contains a list of AtlasSchemaComparison
+	AtlasSchemaComparisonList struct {
+		metav1.TypeMeta `json:",inline"`
+		metav1.ListMeta `json:"metadata,omitempty"`
+
+		Items []AtlasSchemaComparison `json:"items"`
+	}
+	//+kubebuilder:object:root=true
+	//+kubebuilder:subresource:status
+	//
+	// AtlasSchemaComparison is the Schema for the atlasschemacomparisons API.
+	// It periodically compares the schemas of two databases.
+	// +kubebuilder:printcolumn:name="In Sync",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+	// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+	// +kubebuilder:printcolumn:name="Changes",type=integer,JSONPath=`.status.changes`
+	AtlasSchemaComparison struct {
+		metav1.TypeMeta   `json:",inline"`
+		metav1.ObjectMeta `json:"metadata,omitempty"`
+
+		Spec   AtlasSchemaComparisonSpec   `json:"spec,omitempty"`
+		Status AtlasSchemaComparisonStatus `json:"status,omitempty"`
+	}
+	// AtlasSchemaComparisonSpec defines the databases to compare.
+	AtlasSchemaComparisonSpec struct {
+		// Source is the database the diff is computed from.
+		Source ComparisonTarget `json:"source"`
+		// Target is the database the source is compared with. The reported
+		// diff holds the statements that bring the source to the target.
+		Target ComparisonTarget `json:"target"`
+		// DevURL is the URL of the database to use for normalization and calculations.
+		// If it is not specified, the operator will spin up a temporary database container to use for normalization.
+		// +optional
+		DevURL string `json:"devURL,omitempty"`
+		// DevURLFrom is a reference to a secret containing the URL of the database to use for normalization and calculations.
+		// +optional
+		DevURLFrom Secret `json:"devURLFrom,omitempty"`
+		// Schemas limits the comparison to the given schemas.
+		// +optional
+		Schemas []string `json:"schemas,omitempty"`
+		// Exclude a list of glob patterns used to filter existing resources being taken into account.
+		// +optional
+		Exclude []string `json:"exclude,omitempty"`
+		// Interval between two comparisons.
+		// +kubebuilder:default="1h"
+		// +optional
+		Interval *metav1.Duration `json:"interval,omitempty"`
+	}
+	// ComparisonTarget defines a compared database. It is either a database URL,
+	// or the target database of an existing AtlasSchema or AtlasMigration.
+	// +kubebuilder:validation:XValidation:rule="!(has(self.schemaRef) && has(self.migrationRef))",message="only one of schemaRef and migrationRef can be set"
+	ComparisonTarget struct {
+		TargetSpec `json:",inline"`
+		// SchemaRef references the AtlasSchema whose target database is compared.
+		// +optional
+		SchemaRef *corev1.LocalObjectReference `json:"schemaRef,omitempty"`
+		// MigrationRef references the AtlasMigration whose target database is compared.
+		// +optional
+		MigrationRef *corev1.LocalObjectReference `json:"migrationRef,omitempty"`
+	}
+	// AtlasSchemaComparisonStatus defines the observed state of AtlasSchemaComparison
+	AtlasSchemaComparisonStatus struct {
+		// Conditions represent the latest available observations of an object's state.
+		Conditions []metav1.Condition `json:"conditions,omitempty"`
+		// Diff is the SQL that brings the source schema to the target schema.
+		// It is empty if the schemas are in sync.
+		// +optional
+		Diff string `json:"diff,omitempty"`
+		// Changes is the number of statements in the diff.
+		// +optional
+		Changes int `json:"changes,omitempty"`
+		// LastCompared is the unix timestamp of the last comparison.
+		// +optional
+		LastCompared int64 `json:"lastCompared,omitempty"`
+	}
+)
+
+func init() {
+	SchemeBuilder.Register(&AtlasSchemaComparison{}, &AtlasSchemaComparisonList{})
+}
+
+// DatabaseURL returns the URL of the compared database.
+func (t ComparisonTarget) DatabaseURL(ctx context.Context, r client.Reader, ns string) (*url.URL, error) {
+	switch {
+	case t.SchemaRef != nil:
+		sc := &AtlasSchema{}
+		if err := r.Get(ctx, types.NamespacedName{Name: t.SchemaRef.Name, Namespace: ns}, sc); err != nil {
+			return nil, fmt.Errorf("reading AtlasSchema %q: %w", t.SchemaRef.Name, err)
+		}
+		return sc.Spec.DatabaseURL(ctx, r, ns)
+	case t.MigrationRef != nil:
+		mig := &AtlasMigration{}
+		if err := r.Get(ctx, types.NamespacedName{Name: t.MigrationRef.Name, Namespace: ns}, mig); err != nil {
+			return nil, fmt.Errorf("reading AtlasMigration %q: %w", t.MigrationRef.Name, err)
+		}
+		return mig.Spec.DatabaseURL(ctx, r, ns)
+	default:
+		return t.TargetSpec.DatabaseURL(ctx, r, ns)
+	}
+}
+
+// NamespacedName returns the namespaced name of the object.
+func (c *AtlasSchemaComparison) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      c.Name,
+		Namespace: c.Namespace,
+	}
+}
+
+// Interval returns the interval between two comparisons.
+func (c *AtlasSchemaComparison) Interval() time.Duration {
+	if i := c.Spec.Interval; i != nil && i.Duration > 0 {
+		return i.Duration
+	}
+	return time.Hour
+}
+
+// IsReady returns true if the ready condition is true.
+func (c *AtlasSchemaComparison) IsReady() bool {
+	return meta.IsStatusConditionTrue(c.Status.Conditions, readyCond)
+}
+
+// SetInSync sets the ready condition to true, the compared schemas are in sync.
+func (c *AtlasSchemaComparison) SetInSync(status AtlasSchemaComparisonStatus) {
+	c.Status = status
+	meta.SetStatusCondition(&c.Status.Conditions, metav1.Condition{
+		Type:    readyCond,
+		Status:  metav1.ConditionTrue,
+		Reason:  "InSync",
+		Message: "The schemas are in sync",
+	})
+}
+
+// SetDiverged sets the ready condition to false, the compared schemas differ.
+func (c *AtlasSchemaComparison) SetDiverged(status AtlasSchemaComparisonStatus) {
+	c.Status = status
+	meta.SetStatusCondition(&c.Status.Conditions, metav1.Condition{
+		Type:    readyCond,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Diverged",
+		Message: fmt.Sprintf("The schemas differ by %d changes", status.Changes),
+	})
+}
+
+// SetNotReady sets the ready condition to false.
+func (c *AtlasSchemaComparison) SetNotReady(reason, message string) {
+	meta.SetStatusCondition(&c.Status.Conditions, metav1.Condition{
+		Type:    readyCond,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+}
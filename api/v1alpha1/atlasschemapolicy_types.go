@@ -0,0 +1,92 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+type (
+	//+kubebuilder:object:root=true
+	//
+	// AtlasSchemaPolicyList contains a list of AtlasSchemaPolicy
+	AtlasSchemaPolicyList struct {
+		metav1.TypeMeta `json:",inline"`
+		metav1.ListMeta `json:"metadata,omitempty"`
+
+		Items []AtlasSchemaPolicy `json:"items"`
+	}
+	//+kubebuilder:object:root=true
+	//+kubebuilder:resource:scope=Cluster
+	//
+	// AtlasSchemaPolicy is the Schema for the atlasschemapolicies API.
+	// It defines custom rules, written in CEL, that the desired schema of the
+	// matching AtlasSchema resources must satisfy before it is applied.
+	AtlasSchemaPolicy struct {
+		metav1.TypeMeta   `json:",inline"`
+		metav1.ObjectMeta `json:"metadata,omitempty"`
+
+		Spec AtlasSchemaPolicySpec `json:"spec,omitempty"`
+	}
+	// AtlasSchemaPolicySpec defines the rules of the policy and the resources it applies to.
+	AtlasSchemaPolicySpec struct {
+		// Namespaces limits the policy to AtlasSchema resources in the given namespaces.
+		// If empty, the policy applies to all namespaces.
+		// +optional
+		Namespaces []string `json:"namespaces,omitempty"`
+		// Selector limits the policy to AtlasSchema resources matching the label selector.
+		// If empty, the policy applies to all resources.
+		// +optional
+		Selector *metav1.LabelSelector `json:"selector,omitempty"`
+		// Rules are the rules the desired schema must satisfy.
+		// +kubebuilder:validation:MinItems=1
+		Rules []SchemaPolicyRule `json:"rules"`
+	}
+	// SchemaPolicyRule is a single CEL rule of a policy.
+	SchemaPolicyRule struct {
+		// Name of the rule, reported when the rule fails.
+		Name string `json:"name"`
+		// Expression is a CEL expression that must evaluate to true.
+		// The expression has access to the following variables:
+		// - schema: the desired schema, in the JSON format of `atlas schema inspect`.
+		// - changes: the list of SQL statements planned to be applied to the target database.
+		Expression string `json:"expression"`
+		// Message is reported when the rule fails. Defaults to the expression.
+		// +optional
+		Message string `json:"message,omitempty"`
+	}
+)
+
+func init() {
+	SchemeBuilder.Register(&AtlasSchemaPolicy{}, &AtlasSchemaPolicyList{})
+}
+
+// Matches returns true if the policy applies to the given AtlasSchema.
+func (p *AtlasSchemaPolicy) Matches(sc *AtlasSchema) (bool, error) {
+	if len(p.Spec.Namespaces) > 0 && !slices.Contains(p.Spec.Namespaces, sc.Namespace) {
+		return false, nil
+	}
+	if p.Spec.Selector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(p.Spec.Selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Matches(labels.Set(sc.Labels)), nil
+}
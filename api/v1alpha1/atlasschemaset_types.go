@@ -0,0 +1,147 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// LabelSet is the label holding the name of the set that created a resource.
+	LabelSet = "atlasgo.io/set"
+	// LabelTenantOf is the label holding the name of the resource that created a tenant resource.
+	LabelTenantOf = "atlasgo.io/tenant-of"
+)
+
+type (
+	//+kubebuilder:object:root=true
+	//
+	// AtlasSchemaSetList contains a list of AtlasSchemaSet
+	AtlasSchemaSetList struct {
+		metav1.TypeMeta `json:",inline"`
+		metav1.ListMeta `json:"metadata,omitempty"`
+
+		Items []AtlasSchemaSet `json:"items"`
+	}
+	//+kubebuilder:object:root=true
+	//+kubebuilder:subresource:status
+	//
+	// AtlasSchemaSet is the Schema for the atlasschemasets API.
+	// It applies one desired schema to many databases, by creating an AtlasSchema for each target database.
+	// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+	// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+	// +kubebuilder:printcolumn:name="Ready Targets",type=integer,JSONPath=`.status.readyTargets`
+	// +kubebuilder:printcolumn:name="Total",type=integer,JSONPath=`.status.totalTargets`
+	AtlasSchemaSet struct {
+		metav1.TypeMeta   `json:",inline"`
+		metav1.ObjectMeta `json:"metadata,omitempty"`
+
+		Spec   AtlasSchemaSetSpec   `json:"spec,omitempty"`
+		Status AtlasSchemaSetStatus `json:"status,omitempty"`
+	}
+	// AtlasSchemaSetSpec defines the desired state of AtlasSchemaSet
+	AtlasSchemaSetSpec struct {
+		// Generator generates the target databases of the set.
+		Generator TargetGenerator `json:"generator"`
+		// Template is the template of the AtlasSchema resources created for the targets.
+		// The target database of the template is replaced by the generated one.
+		Template AtlasSchemaTemplate `json:"template"`
+	}
+	// AtlasSchemaTemplate is the template of the AtlasSchema resources of a set.
+	AtlasSchemaTemplate struct {
+		// +optional
+		Metadata TemplateMeta    `json:"metadata,omitempty"`
+		Spec     AtlasSchemaSpec `json:"spec"`
+	}
+	// AtlasSchemaSetStatus defines the observed state of AtlasSchemaSet
+	AtlasSchemaSetStatus struct {
+		// Conditions represent the latest available observations of an object's state.
+		Conditions []metav1.Condition `json:"conditions,omitempty"`
+		// Targets is the state of the target databases, ordered by name.
+		// +optional
+		Targets []SetTargetStatus `json:"targets,omitempty"`
+		// ReadyTargets is the number of ready targets.
+		ReadyTargets int `json:"readyTargets"`
+		// TotalTargets is the number of targets.
+		TotalTargets int `json:"totalTargets"`
+	}
+)
+
+func init() {
+	SchemeBuilder.Register(&AtlasSchemaSet{}, &AtlasSchemaSetList{})
+}
+
+// NamespacedName returns the namespaced name of the object.
+func (s *AtlasSchemaSet) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      s.Name,
+		Namespace: s.Namespace,
+	}
+}
+
+// IsReady returns true if the ready condition is true.
+func (s *AtlasSchemaSet) IsReady() bool {
+	return meta.IsStatusConditionTrue(s.Status.Conditions, readyCond)
+}
+
+// SetTargets sets the state of the targets, and the ready condition to true
+// if all the targets are ready.
+func (s *AtlasSchemaSet) SetTargets(targets []SetTargetStatus) {
+	s.Status.Targets = targets
+	s.Status.ReadyTargets, s.Status.TotalTargets = countReady(targets), len(targets)
+	meta.SetStatusCondition(&s.Status.Conditions, setCondition(s.Status.ReadyTargets, s.Status.TotalTargets, "targets"))
+}
+
+// SetNotReady sets the ready condition to false.
+func (s *AtlasSchemaSet) SetNotReady(reason, message string) {
+	meta.SetStatusCondition(&s.Status.Conditions, metav1.Condition{
+		Type:    readyCond,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// countReady returns the number of ready targets.
+func countReady(targets []SetTargetStatus) (n int) {
+	for _, t := range targets {
+		if t.Ready {
+			n++
+		}
+	}
+	return n
+}
+
+// setCondition returns the ready condition of a set of targets or tenants.
+func setCondition(ready, total int, noun string) metav1.Condition {
+	if ready == total {
+		return metav1.Condition{
+			Type:    readyCond,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Applied",
+			Message: fmt.Sprintf("All %d %s are ready", total, noun),
+		}
+	}
+	return metav1.Condition{
+		Type:    readyCond,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Progressing",
+		Message: fmt.Sprintf("%d/%d %s are ready", ready, total, noun),
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type (
+	//+kubebuilder:object:root=true
+	//
+	// AtlasSeedList contains a list of AtlasSeed
+	AtlasSeedList struct {
+		metav1.TypeMeta `json:",inline"`
+		metav1.ListMeta `json:"metadata,omitempty"`
+
+		Items []AtlasSeed `json:"items"`
+	}
+	//+kubebuilder:object:root=true
+	//+kubebuilder:subresource:status
+	//
+	// AtlasSeed is the Schema for the atlasseeds API.
+	// It keeps the reference data of a database in sync, once its schema is applied.
+	// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+	// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+	// +kubebuilder:printcolumn:name="Rows",type=integer,JSONPath=`.status.rows`
+	AtlasSeed struct {
+		metav1.TypeMeta   `json:",inline"`
+		metav1.ObjectMeta `json:"metadata,omitempty"`
+
+		Spec   AtlasSeedSpec   `json:"spec,omitempty"`
+		Status AtlasSeedStatus `json:"status,omitempty"`
+	}
+	// AtlasSeedSpec defines the seeded data and the database it is applied to.
+	// +kubebuilder:validation:XValidation:rule="has(self.schemaRef) != has(self.migrationRef)",message="exactly one of schemaRef and migrationRef must be set"
+	// +kubebuilder:validation:XValidation:rule="!has(self.minVersion) || has(self.migrationRef)",message="minVersion requires migrationRef"
+	AtlasSeedSpec struct {
+		// SchemaRef references the AtlasSchema whose target database is seeded.
+		// The data is applied once the AtlasSchema is ready.
+		// +optional
+		SchemaRef *corev1.LocalObjectReference `json:"schemaRef,omitempty"`
+		// MigrationRef references the AtlasMigration whose target database is seeded.
+		// The data is applied once the AtlasMigration is ready.
+		// +optional
+		MigrationRef *corev1.LocalObjectReference `json:"migrationRef,omitempty"`
+		// MinVersion is the minimum migration version the AtlasMigration must have applied
+		// before the data is applied.
+		// +optional
+		MinVersion string `json:"minVersion,omitempty"`
+		// ConfigMapRef references the ConfigMap holding the seed data. Keys ending with ".sql"
+		// hold SQL statements, and keys ending with ".yaml", ".yml" or ".json" hold rows that
+		// are upserted into a table. The keys are applied in lexical order, in one transaction.
+		ConfigMapRef corev1.LocalObjectReference `json:"configMapRef"`
+	}
+	// AtlasSeedStatus defines the observed state of AtlasSeed
+	AtlasSeedStatus struct {
+		// Conditions represent the latest available observations of an object's state.
+		Conditions []metav1.Condition `json:"conditions,omitempty"`
+		// ObservedHash is the hash of the most recently applied data.
+		// +optional
+		ObservedHash string `json:"observed_hash,omitempty"`
+		// Statements is the number of SQL statements applied.
+		// +optional
+		Statements int `json:"statements,omitempty"`
+		// Rows is the number of upserted rows.
+		// +optional
+		Rows int `json:"rows,omitempty"`
+		// LastApplied is the unix timestamp of the most recent apply.
+		// +optional
+		LastApplied int64 `json:"lastApplied,omitempty"`
+	}
+)
+
+func init() {
+	SchemeBuilder.Register(&AtlasSeed{}, &AtlasSeedList{})
+}
+
+// NamespacedName returns the namespaced name of the object.
+func (s *AtlasSeed) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      s.Name,
+		Namespace: s.Namespace,
+	}
+}
+
+// IsReady returns true if the ready condition is true.
+func (s *AtlasSeed) IsReady() bool {
+	return meta.IsStatusConditionTrue(s.Status.Conditions, readyCond)
+}
+
+// IsHashModified returns true if the hash is different from the observed hash.
+func (s *AtlasSeed) IsHashModified(hash string) bool {
+	return hash != s.Status.ObservedHash
+}
+
+// SetReady sets the ready condition to true.
+func (s *AtlasSeed) SetReady(status AtlasSeedStatus) {
+	s.Status = status
+	meta.SetStatusCondition(&s.Status.Conditions, metav1.Condition{
+		Type:   readyCond,
+		Status: metav1.ConditionTrue,
+		Reason: "Applied",
+	})
+}
+
+// SetNotReady sets the ready condition to false.
+func (s *AtlasSeed) SetNotReady(reason, message string) {
+	meta.SetStatusCondition(&s.Status.Conditions, metav1.Condition{
+		Type:    readyCond,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+}
@@ -19,8 +19,10 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -35,6 +37,10 @@ type (
 		// Credentials defines the credentials to use when connecting to the database.
 		// Used instead of URL or URLFrom.
 		Credentials Credentials `json:"credentials,omitempty"`
+		// Tenant binds the connection to the schema of a tenant in the target database, by setting
+		// the search_path on PostgreSQL, or the database of the URL on MySQL and MariaDB.
+		// +optional
+		Tenant string `json:"tenant,omitempty"`
 	}
 	// Credentials defines the credentials to use when connecting to the database.
 	Credentials struct {
@@ -60,11 +66,195 @@ type (
 		TokenFrom TokenFrom `json:"tokenFrom,omitempty"`
 		// Repo is the name of repository on the Atlas Cloud.
 		Repo string `json:"repo,omitempty"`
+		// URL of the Atlas Cloud instance. Defaults to the public Atlas Cloud.
+		URL string `json:"url,omitempty"`
+		// Project is the name of the project the runs are reported to on the Atlas Cloud.
+		Project string `json:"project,omitempty"`
+	}
+	// Tests defines the Atlas tests to run against the dev database
+	// before changes are applied to the target database.
+	Tests struct {
+		// ConfigMapRef references the ConfigMap holding the test files.
+		// Every key ending with ".hcl" is loaded as a test file.
+		ConfigMapRef corev1.LocalObjectReference `json:"configMapRef"`
+		// Run filters the test cases to run by a regular expression.
+		// +optional
+		Run string `json:"run,omitempty"`
+	}
+	// Timeouts defines the session timeouts of the connections to the target database,
+	// set with the session settings of each driver: lock_timeout and statement_timeout
+	// on PostgreSQL, lock_wait_timeout on MySQL, and lock_wait_timeout and max_statement_time
+	// on MariaDB. Timeouts that are not supported for the target database (e.g. on SQL Server)
+	// are ignored, and reported with a warning event.
+	Timeouts struct {
+		// Lock is the maximum time a statement waits for a lock. Changes that fail
+		// on a lock timeout are retried with an increasing backoff.
+		// +optional
+		Lock *metav1.Duration `json:"lock,omitempty"`
+		// Statement is the maximum execution time of a statement. It is ignored on MySQL,
+		// where max_execution_time limits only SELECT statements.
+		// +optional
+		Statement *metav1.Duration `json:"statement,omitempty"`
+	}
+	// Tenants defines the tenant schemas of a database. The desired schema or migration directory
+	// is applied to every tenant schema by a child resource, bound to the schema of the tenant.
+	// +kubebuilder:validation:XValidation:rule="has(self.schemas) != has(self.pattern)",message="exactly one of schemas and pattern must be set"
+	Tenants struct {
+		// Schemas is an explicit list of tenant schemas.
+		// +optional
+		Schemas []string `json:"schemas,omitempty"`
+		// Pattern is a glob pattern matching the tenant schemas of the target database, e.g. "tenant_*".
+		// +optional
+		Pattern string `json:"pattern,omitempty"`
+		// Interval between two discoveries of the schemas matching the pattern.
+		// +kubebuilder:default="5m"
+		// +optional
+		Interval *metav1.Duration `json:"interval,omitempty"`
+	}
+	// TargetGenerator generates the target databases of a set.
+	// +kubebuilder:validation:XValidation:rule="(has(self.secrets) ? 1 : 0) + (has(self.list) ? 1 : 0) + (has(self.query) ? 1 : 0) == 1",message="exactly one of secrets, list and query must be set"
+	TargetGenerator struct {
+		// Secrets generates a target database for every Secret matching the selector.
+		// +optional
+		Secrets *SecretsGenerator `json:"secrets,omitempty"`
+		// List is an explicit list of target databases.
+		// +optional
+		List []GeneratedTarget `json:"list,omitempty"`
+		// Query generates a target database for every row returned by a query against a catalog database.
+		// +optional
+		Query *QueryGenerator `json:"query,omitempty"`
+	}
+	// QueryGenerator runs a read-only query against a catalog database on an interval,
+	// and turns each returned row into a target database. The columns of a row are
+	// available in the templates by their names, e.g. "{{ .host }}".
+	QueryGenerator struct {
+		// Catalog is the database holding the list of the targets.
+		Catalog TargetSpec `json:"catalog"`
+		// Query is the query returning a row per target. It is run in a read-only transaction.
+		Query string `json:"query"`
+		// Name is the template of the target name. Characters that are not allowed
+		// in resource names are replaced with dashes.
+		// +kubebuilder:default="{{ .name }}"
+		// +optional
+		Name string `json:"name,omitempty"`
+		// URL is the template of the target URL, e.g. "postgres://app@{{ .host }}:5432/{{ .db }}".
+		// The values of the row are escaped, and cannot change the structure of the URL.
+		URL string `json:"url"`
+		// Hosts lists the glob patterns of the hosts the generated URLs may point to,
+		// e.g. "db-*.tenants.svc". Defaults to the host of the catalog database.
+		// +optional
+		Hosts []string `json:"hosts,omitempty"`
+		// PasswordFrom references the Secret key holding the password of the targets,
+		// to keep it out of the generated resources.
+		// +optional
+		PasswordFrom Secret `json:"passwordFrom,omitempty"`
+		// Interval between two runs of the query.
+		// +kubebuilder:default="5m"
+		// +optional
+		Interval *metav1.Duration `json:"interval,omitempty"`
+	}
+	// SecretsGenerator selects the Secrets holding the URLs of the target databases.
+	SecretsGenerator struct {
+		// Selector is a label selector over the Secrets in the namespace of the set.
+		Selector metav1.LabelSelector `json:"selector"`
+		// Key is the key of the database URL in the selected Secrets.
+		// +kubebuilder:default="url"
+		// +optional
+		Key string `json:"key,omitempty"`
+	}
+	// GeneratedTarget is a target database of a set.
+	GeneratedTarget struct {
+		// Name identifies the target in the set. The child resource
+		// of the target is named after the set and the target.
+		// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+		Name       string `json:"name"`
+		TargetSpec `json:",inline"`
+	}
+	// TemplateMeta is the metadata of the resources created from a template.
+	TemplateMeta struct {
+		// +optional
+		Labels map[string]string `json:"labels,omitempty"`
+		// +optional
+		Annotations map[string]string `json:"annotations,omitempty"`
+	}
+	// SetTargetStatus is the state of a target database of a set, or of a tenant schema.
+	SetTargetStatus struct {
+		// Name of the target, or the tenant schema.
+		Name string `json:"name"`
+		// Resource is the name of the child resource managing the target.
+		Resource string `json:"resource"`
+		// Ready is true if the child resource is ready.
+		Ready bool `json:"ready"`
+		// Reason is the reason of the ready condition of the child resource.
+		// +optional
+		Reason string `json:"reason,omitempty"`
+		// Message is the message of the ready condition of the child resource.
+		// +optional
+		Message string `json:"message,omitempty"`
+		// Version is the last applied migration version of the target.
+		// +optional
+		Version string `json:"version,omitempty"`
 	}
 )
 
+// DiscoveryInterval returns the interval between two discoveries of the tenant schemas.
+func (t *Tenants) DiscoveryInterval() time.Duration {
+	if i := t.Interval; i != nil && i.Duration > 0 {
+		return i.Duration
+	}
+	return 5 * time.Minute
+}
+
+// NameTemplate returns the template of the target name.
+func (g QueryGenerator) NameTemplate() string {
+	if g.Name != "" {
+		return g.Name
+	}
+	return "{{ .name }}"
+}
+
+// QueryInterval returns the interval between two runs of the query.
+func (g QueryGenerator) QueryInterval() time.Duration {
+	if i := g.Interval; i != nil && i.Duration > 0 {
+		return i.Duration
+	}
+	return 5 * time.Minute
+}
+
+// SecretKey returns the key of the database URL in the selected Secrets.
+func (g SecretsGenerator) SecretKey() string {
+	if g.Key != "" {
+		return g.Key
+	}
+	return "url"
+}
+
 // DatabaseURL returns the database url.
 func (s TargetSpec) DatabaseURL(ctx context.Context, r client.Reader, ns string) (*url.URL, error) {
+	u, err := s.databaseURL(ctx, r, ns)
+	if err != nil || s.Tenant == "" {
+		return u, err
+	}
+	return BindSchema(u, s.Tenant)
+}
+
+// BindSchema returns a copy of the URL bound to the given schema.
+func BindSchema(u *url.URL, schema string) (*url.URL, error) {
+	c := *u
+	switch strings.ToLower(strings.SplitN(u.Scheme, "+", 2)[0]) {
+	case "postgres", "postgresql", "redshift":
+		q := c.Query()
+		q.Set("search_path", schema)
+		c.RawQuery = q.Encode()
+	case "mysql", "mariadb", "maria", "clickhouse":
+		c.Path = "/" + schema
+	default:
+		return nil, fmt.Errorf("binding a schema is not supported for scheme %q", u.Scheme)
+	}
+	return &c, nil
+}
+
+func (s TargetSpec) databaseURL(ctx context.Context, r client.Reader, ns string) (*url.URL, error) {
 	if s.URLFrom.SecretKeyRef != nil {
 		val, err := getSecrectValue(ctx, r, ns, s.URLFrom.SecretKeyRef)
 		if err != nil {
@@ -83,6 +83,206 @@ func (in *AtlasMigrationList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasMigrationOperation) DeepCopyInto(out *AtlasMigrationOperation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasMigrationOperation.
+func (in *AtlasMigrationOperation) DeepCopy() *AtlasMigrationOperation {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasMigrationOperation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AtlasMigrationOperation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasMigrationOperationList) DeepCopyInto(out *AtlasMigrationOperationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AtlasMigrationOperation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasMigrationOperationList.
+func (in *AtlasMigrationOperationList) DeepCopy() *AtlasMigrationOperationList {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasMigrationOperationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AtlasMigrationOperationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasMigrationOperationSpec) DeepCopyInto(out *AtlasMigrationOperationSpec) {
+	*out = *in
+	out.MigrationRef = in.MigrationRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasMigrationOperationSpec.
+func (in *AtlasMigrationOperationSpec) DeepCopy() *AtlasMigrationOperationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasMigrationOperationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasMigrationOperationStatus) DeepCopyInto(out *AtlasMigrationOperationStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasMigrationOperationStatus.
+func (in *AtlasMigrationOperationStatus) DeepCopy() *AtlasMigrationOperationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasMigrationOperationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasMigrationSet) DeepCopyInto(out *AtlasMigrationSet) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasMigrationSet.
+func (in *AtlasMigrationSet) DeepCopy() *AtlasMigrationSet {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasMigrationSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AtlasMigrationSet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasMigrationSetList) DeepCopyInto(out *AtlasMigrationSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AtlasMigrationSet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasMigrationSetList.
+func (in *AtlasMigrationSetList) DeepCopy() *AtlasMigrationSetList {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasMigrationSetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AtlasMigrationSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasMigrationSetSpec) DeepCopyInto(out *AtlasMigrationSetSpec) {
+	*out = *in
+	in.Generator.DeepCopyInto(&out.Generator)
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasMigrationSetSpec.
+func (in *AtlasMigrationSetSpec) DeepCopy() *AtlasMigrationSetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasMigrationSetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasMigrationSetStatus) DeepCopyInto(out *AtlasMigrationSetStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]SetTargetStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasMigrationSetStatus.
+func (in *AtlasMigrationSetStatus) DeepCopy() *AtlasMigrationSetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasMigrationSetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AtlasMigrationSpec) DeepCopyInto(out *AtlasMigrationSpec) {
 	*out = *in
@@ -90,11 +290,50 @@ func (in *AtlasMigrationSpec) DeepCopyInto(out *AtlasMigrationSpec) {
 	in.Cloud.DeepCopyInto(&out.Cloud)
 	in.Dir.DeepCopyInto(&out.Dir)
 	in.DevURLFrom.DeepCopyInto(&out.DevURLFrom)
+	if in.DevLabels != nil {
+		in, out := &in.DevLabels, &out.DevLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DevAnnotations != nil {
+		in, out := &in.DevAnnotations, &out.DevAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.HandoverFrom != nil {
+		in, out := &in.HandoverFrom, &out.HandoverFrom
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
 	if in.ProtectedFlows != nil {
 		in, out := &in.ProtectedFlows, &out.ProtectedFlows
 		*out = new(ProtectFlows)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Policy != nil {
+		in, out := &in.Policy, &out.Policy
+		*out = new(MigrationPolicy)
+		**out = **in
+	}
+	if in.Tests != nil {
+		in, out := &in.Tests, &out.Tests
+		*out = new(Tests)
+		**out = **in
+	}
+	if in.Timeouts != nil {
+		in, out := &in.Timeouts, &out.Timeouts
+		*out = new(Timeouts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tenants != nil {
+		in, out := &in.Tenants, &out.Tenants
+		*out = new(Tenants)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasMigrationSpec.
@@ -117,6 +356,11 @@ func (in *AtlasMigrationStatus) DeepCopyInto(out *AtlasMigrationStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Tenants != nil {
+		in, out := &in.Tenants, &out.Tenants
+		*out = make([]SetTargetStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasMigrationStatus.
@@ -129,6 +373,23 @@ func (in *AtlasMigrationStatus) DeepCopy() *AtlasMigrationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasMigrationTemplate) DeepCopyInto(out *AtlasMigrationTemplate) {
+	*out = *in
+	in.Metadata.DeepCopyInto(&out.Metadata)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasMigrationTemplate.
+func (in *AtlasMigrationTemplate) DeepCopy() *AtlasMigrationTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasMigrationTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AtlasSchema) DeepCopyInto(out *AtlasSchema) {
 	*out = *in
@@ -157,31 +418,58 @@ func (in *AtlasSchema) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *AtlasSchemaList) DeepCopyInto(out *AtlasSchemaList) {
+func (in *AtlasSchemaComparison) DeepCopyInto(out *AtlasSchemaComparison) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaComparison.
+func (in *AtlasSchemaComparison) DeepCopy() *AtlasSchemaComparison {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSchemaComparison)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AtlasSchemaComparison) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSchemaComparisonList) DeepCopyInto(out *AtlasSchemaComparisonList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]AtlasSchema, len(*in))
+		*out = make([]AtlasSchemaComparison, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaList.
-func (in *AtlasSchemaList) DeepCopy() *AtlasSchemaList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaComparisonList.
+func (in *AtlasSchemaComparisonList) DeepCopy() *AtlasSchemaComparisonList {
 	if in == nil {
 		return nil
 	}
-	out := new(AtlasSchemaList)
+	out := new(AtlasSchemaComparisonList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *AtlasSchemaList) DeepCopyObject() runtime.Object {
+func (in *AtlasSchemaComparisonList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -189,41 +477,40 @@ func (in *AtlasSchemaList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *AtlasSchemaSpec) DeepCopyInto(out *AtlasSchemaSpec) {
+func (in *AtlasSchemaComparisonSpec) DeepCopyInto(out *AtlasSchemaComparisonSpec) {
 	*out = *in
-	in.TargetSpec.DeepCopyInto(&out.TargetSpec)
-	in.Schema.DeepCopyInto(&out.Schema)
-	in.Cloud.DeepCopyInto(&out.Cloud)
+	in.Source.DeepCopyInto(&out.Source)
+	in.Target.DeepCopyInto(&out.Target)
 	in.DevURLFrom.DeepCopyInto(&out.DevURLFrom)
-	if in.Exclude != nil {
-		in, out := &in.Exclude, &out.Exclude
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.Policy != nil {
-		in, out := &in.Policy, &out.Policy
-		*out = new(Policy)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Schemas != nil {
-		in, out := &in.Schemas, &out.Schemas
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaSpec.
-func (in *AtlasSchemaSpec) DeepCopy() *AtlasSchemaSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaComparisonSpec.
+func (in *AtlasSchemaComparisonSpec) DeepCopy() *AtlasSchemaComparisonSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(AtlasSchemaSpec)
+	out := new(AtlasSchemaComparisonSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *AtlasSchemaStatus) DeepCopyInto(out *AtlasSchemaStatus) {
+func (in *AtlasSchemaComparisonStatus) DeepCopyInto(out *AtlasSchemaComparisonStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
@@ -234,27 +521,521 @@ func (in *AtlasSchemaStatus) DeepCopyInto(out *AtlasSchemaStatus) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaStatus.
-func (in *AtlasSchemaStatus) DeepCopy() *AtlasSchemaStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaComparisonStatus.
+func (in *AtlasSchemaComparisonStatus) DeepCopy() *AtlasSchemaComparisonStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(AtlasSchemaStatus)
+	out := new(AtlasSchemaComparisonStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CheckConfig) DeepCopyInto(out *CheckConfig) {
+func (in *AtlasSchemaList) DeepCopyInto(out *AtlasSchemaList) {
 	*out = *in
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CheckConfig.
-func (in *CheckConfig) DeepCopy() *CheckConfig {
-	if in == nil {
-		return nil
-	}
-	out := new(CheckConfig)
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AtlasSchema, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaList.
+func (in *AtlasSchemaList) DeepCopy() *AtlasSchemaList {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSchemaList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AtlasSchemaList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSchemaPolicy) DeepCopyInto(out *AtlasSchemaPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaPolicy.
+func (in *AtlasSchemaPolicy) DeepCopy() *AtlasSchemaPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSchemaPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AtlasSchemaPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSchemaPolicyList) DeepCopyInto(out *AtlasSchemaPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AtlasSchemaPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaPolicyList.
+func (in *AtlasSchemaPolicyList) DeepCopy() *AtlasSchemaPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSchemaPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AtlasSchemaPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSchemaPolicySpec) DeepCopyInto(out *AtlasSchemaPolicySpec) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]SchemaPolicyRule, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaPolicySpec.
+func (in *AtlasSchemaPolicySpec) DeepCopy() *AtlasSchemaPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSchemaPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSchemaSet) DeepCopyInto(out *AtlasSchemaSet) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaSet.
+func (in *AtlasSchemaSet) DeepCopy() *AtlasSchemaSet {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSchemaSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AtlasSchemaSet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSchemaSetList) DeepCopyInto(out *AtlasSchemaSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AtlasSchemaSet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaSetList.
+func (in *AtlasSchemaSetList) DeepCopy() *AtlasSchemaSetList {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSchemaSetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AtlasSchemaSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSchemaSetSpec) DeepCopyInto(out *AtlasSchemaSetSpec) {
+	*out = *in
+	in.Generator.DeepCopyInto(&out.Generator)
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaSetSpec.
+func (in *AtlasSchemaSetSpec) DeepCopy() *AtlasSchemaSetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSchemaSetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSchemaSetStatus) DeepCopyInto(out *AtlasSchemaSetStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]SetTargetStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaSetStatus.
+func (in *AtlasSchemaSetStatus) DeepCopy() *AtlasSchemaSetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSchemaSetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSchemaSpec) DeepCopyInto(out *AtlasSchemaSpec) {
+	*out = *in
+	in.TargetSpec.DeepCopyInto(&out.TargetSpec)
+	in.Schema.DeepCopyInto(&out.Schema)
+	in.Cloud.DeepCopyInto(&out.Cloud)
+	if in.DevLabels != nil {
+		in, out := &in.DevLabels, &out.DevLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DevAnnotations != nil {
+		in, out := &in.DevAnnotations, &out.DevAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.DevURLFrom.DeepCopyInto(&out.DevURLFrom)
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Policy != nil {
+		in, out := &in.Policy, &out.Policy
+		*out = new(Policy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Inspect != nil {
+		in, out := &in.Inspect, &out.Inspect
+		*out = new(InspectSpec)
+		**out = **in
+	}
+	if in.Docs != nil {
+		in, out := &in.Docs, &out.Docs
+		*out = new(DocsSpec)
+		**out = **in
+	}
+	if in.Tests != nil {
+		in, out := &in.Tests, &out.Tests
+		*out = new(Tests)
+		**out = **in
+	}
+	if in.Timeouts != nil {
+		in, out := &in.Timeouts, &out.Timeouts
+		*out = new(Timeouts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(Rollout)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OnlineSchemaChange != nil {
+		in, out := &in.OnlineSchemaChange, &out.OnlineSchemaChange
+		*out = new(OnlineSchemaChange)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Output != nil {
+		in, out := &in.Output, &out.Output
+		*out = new(Output)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tenants != nil {
+		in, out := &in.Tenants, &out.Tenants
+		*out = new(Tenants)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaSpec.
+func (in *AtlasSchemaSpec) DeepCopy() *AtlasSchemaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSchemaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSchemaStatus) DeepCopyInto(out *AtlasSchemaStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ObservedHashes != nil {
+		in, out := &in.ObservedHashes, &out.ObservedHashes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Diagnostics != nil {
+		in, out := &in.Diagnostics, &out.Diagnostics
+		*out = make([]LintDiagnostic, len(*in))
+		copy(*out, *in)
+	}
+	if in.Impact != nil {
+		in, out := &in.Impact, &out.Impact
+		*out = make([]TableImpact, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OnlineSchemaChange != nil {
+		in, out := &in.OnlineSchemaChange, &out.OnlineSchemaChange
+		*out = new(OnlineSchemaChangeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tenants != nil {
+		in, out := &in.Tenants, &out.Tenants
+		*out = make([]SetTargetStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaStatus.
+func (in *AtlasSchemaStatus) DeepCopy() *AtlasSchemaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSchemaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSchemaTemplate) DeepCopyInto(out *AtlasSchemaTemplate) {
+	*out = *in
+	in.Metadata.DeepCopyInto(&out.Metadata)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSchemaTemplate.
+func (in *AtlasSchemaTemplate) DeepCopy() *AtlasSchemaTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSchemaTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSeed) DeepCopyInto(out *AtlasSeed) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSeed.
+func (in *AtlasSeed) DeepCopy() *AtlasSeed {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSeed)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AtlasSeed) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSeedList) DeepCopyInto(out *AtlasSeedList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AtlasSeed, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSeedList.
+func (in *AtlasSeedList) DeepCopy() *AtlasSeedList {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSeedList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AtlasSeedList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSeedSpec) DeepCopyInto(out *AtlasSeedSpec) {
+	*out = *in
+	if in.SchemaRef != nil {
+		in, out := &in.SchemaRef, &out.SchemaRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.MigrationRef != nil {
+		in, out := &in.MigrationRef, &out.MigrationRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	out.ConfigMapRef = in.ConfigMapRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSeedSpec.
+func (in *AtlasSeedSpec) DeepCopy() *AtlasSeedSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSeedSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtlasSeedStatus) DeepCopyInto(out *AtlasSeedStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtlasSeedStatus.
+func (in *AtlasSeedStatus) DeepCopy() *AtlasSeedStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AtlasSeedStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CheckConfig) DeepCopyInto(out *CheckConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CheckConfig.
+func (in *CheckConfig) DeepCopy() *CheckConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CheckConfig)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -265,156 +1046,415 @@ func (in *Cloud) DeepCopyInto(out *Cloud) {
 	in.TokenFrom.DeepCopyInto(&out.TokenFrom)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Cloud.
-func (in *Cloud) DeepCopy() *Cloud {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Cloud.
+func (in *Cloud) DeepCopy() *Cloud {
+	if in == nil {
+		return nil
+	}
+	out := new(Cloud)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudV0) DeepCopyInto(out *CloudV0) {
+	*out = *in
+	in.TokenFrom.DeepCopyInto(&out.TokenFrom)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudV0.
+func (in *CloudV0) DeepCopy() *CloudV0 {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudV0)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComparisonTarget) DeepCopyInto(out *ComparisonTarget) {
+	*out = *in
+	in.TargetSpec.DeepCopyInto(&out.TargetSpec)
+	if in.SchemaRef != nil {
+		in, out := &in.SchemaRef, &out.SchemaRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.MigrationRef != nil {
+		in, out := &in.MigrationRef, &out.MigrationRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComparisonTarget.
+func (in *ComparisonTarget) DeepCopy() *ComparisonTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(ComparisonTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConcurrentIndex) DeepCopyInto(out *ConcurrentIndex) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConcurrentIndex.
+func (in *ConcurrentIndex) DeepCopy() *ConcurrentIndex {
+	if in == nil {
+		return nil
+	}
+	out := new(ConcurrentIndex)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContractGate) DeepCopyInto(out *ContractGate) {
+	*out = *in
+	if in.DeploymentRef != nil {
+		in, out := &in.DeploymentRef, &out.DeploymentRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Delay != nil {
+		in, out := &in.Delay, &out.Delay
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContractGate.
+func (in *ContractGate) DeepCopy() *ContractGate {
+	if in == nil {
+		return nil
+	}
+	out := new(ContractGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Credentials) DeepCopyInto(out *Credentials) {
+	*out = *in
+	in.UserFrom.DeepCopyInto(&out.UserFrom)
+	in.PasswordFrom.DeepCopyInto(&out.PasswordFrom)
+	in.HostFrom.DeepCopyInto(&out.HostFrom)
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Credentials.
+func (in *Credentials) DeepCopy() *Credentials {
+	if in == nil {
+		return nil
+	}
+	out := new(Credentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentFlow) DeepCopyInto(out *DeploymentFlow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentFlow.
+func (in *DeploymentFlow) DeepCopy() *DeploymentFlow {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentFlow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Diff) DeepCopyInto(out *Diff) {
+	*out = *in
+	if in.ConcurrentIndex != nil {
+		in, out := &in.ConcurrentIndex, &out.ConcurrentIndex
+		*out = new(ConcurrentIndex)
+		**out = **in
+	}
+	if in.Skip != nil {
+		in, out := &in.Skip, &out.Skip
+		*out = new(SkipChanges)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Diff.
+func (in *Diff) DeepCopy() *Diff {
+	if in == nil {
+		return nil
+	}
+	out := new(Diff)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Dir) DeepCopyInto(out *Dir) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	out.Remote = in.Remote
+	if in.Local != nil {
+		in, out := &in.Local, &out.Local
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Dir.
+func (in *Dir) DeepCopy() *Dir {
+	if in == nil {
+		return nil
+	}
+	out := new(Dir)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DocsSpec) DeepCopyInto(out *DocsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DocsSpec.
+func (in *DocsSpec) DeepCopy() *DocsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DocsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GeneratedTarget) DeepCopyInto(out *GeneratedTarget) {
+	*out = *in
+	in.TargetSpec.DeepCopyInto(&out.TargetSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GeneratedTarget.
+func (in *GeneratedTarget) DeepCopy() *GeneratedTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(GeneratedTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Impact) DeepCopyInto(out *Impact) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Impact.
+func (in *Impact) DeepCopy() *Impact {
+	if in == nil {
+		return nil
+	}
+	out := new(Impact)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InspectSpec) DeepCopyInto(out *InspectSpec) {
+	*out = *in
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InspectSpec.
+func (in *InspectSpec) DeepCopy() *InspectSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(InspectSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Lint) DeepCopyInto(out *Lint) {
+	*out = *in
+	if in.Destructive != nil {
+		in, out := &in.Destructive, &out.Destructive
+		*out = new(CheckConfig)
+		**out = **in
+	}
+	if in.DataDepend != nil {
+		in, out := &in.DataDepend, &out.DataDepend
+		*out = new(CheckConfig)
+		**out = **in
+	}
+	if in.Incompatible != nil {
+		in, out := &in.Incompatible, &out.Incompatible
+		*out = new(CheckConfig)
+		**out = **in
+	}
+	if in.Naming != nil {
+		in, out := &in.Naming, &out.Naming
+		*out = new(NamingCheck)
+		**out = **in
+	}
+	if in.ConcurrentIndex != nil {
+		in, out := &in.ConcurrentIndex, &out.ConcurrentIndex
+		*out = new(CheckConfig)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Lint.
+func (in *Lint) DeepCopy() *Lint {
 	if in == nil {
 		return nil
 	}
-	out := new(Cloud)
+	out := new(Lint)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CloudV0) DeepCopyInto(out *CloudV0) {
+func (in *LintDiagnostic) DeepCopyInto(out *LintDiagnostic) {
 	*out = *in
-	in.TokenFrom.DeepCopyInto(&out.TokenFrom)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudV0.
-func (in *CloudV0) DeepCopy() *CloudV0 {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LintDiagnostic.
+func (in *LintDiagnostic) DeepCopy() *LintDiagnostic {
 	if in == nil {
 		return nil
 	}
-	out := new(CloudV0)
+	out := new(LintDiagnostic)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ConcurrentIndex) DeepCopyInto(out *ConcurrentIndex) {
+func (in *MigrationPolicy) DeepCopyInto(out *MigrationPolicy) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConcurrentIndex.
-func (in *ConcurrentIndex) DeepCopy() *ConcurrentIndex {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPolicy.
+func (in *MigrationPolicy) DeepCopy() *MigrationPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(ConcurrentIndex)
+	out := new(MigrationPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Credentials) DeepCopyInto(out *Credentials) {
+func (in *MigrationsOutput) DeepCopyInto(out *MigrationsOutput) {
 	*out = *in
-	in.UserFrom.DeepCopyInto(&out.UserFrom)
-	in.PasswordFrom.DeepCopyInto(&out.PasswordFrom)
-	in.HostFrom.DeepCopyInto(&out.HostFrom)
-	if in.Parameters != nil {
-		in, out := &in.Parameters, &out.Parameters
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Credentials.
-func (in *Credentials) DeepCopy() *Credentials {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationsOutput.
+func (in *MigrationsOutput) DeepCopy() *MigrationsOutput {
 	if in == nil {
 		return nil
 	}
-	out := new(Credentials)
+	out := new(MigrationsOutput)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DeploymentFlow) DeepCopyInto(out *DeploymentFlow) {
+func (in *NamingCheck) DeepCopyInto(out *NamingCheck) {
 	*out = *in
+	out.CheckConfig = in.CheckConfig
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentFlow.
-func (in *DeploymentFlow) DeepCopy() *DeploymentFlow {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamingCheck.
+func (in *NamingCheck) DeepCopy() *NamingCheck {
 	if in == nil {
 		return nil
 	}
-	out := new(DeploymentFlow)
+	out := new(NamingCheck)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Diff) DeepCopyInto(out *Diff) {
+func (in *OnlineSchemaChange) DeepCopyInto(out *OnlineSchemaChange) {
 	*out = *in
-	if in.ConcurrentIndex != nil {
-		in, out := &in.ConcurrentIndex, &out.ConcurrentIndex
-		*out = new(ConcurrentIndex)
-		**out = **in
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	if in.Skip != nil {
-		in, out := &in.Skip, &out.Skip
-		*out = new(SkipChanges)
-		**out = **in
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Diff.
-func (in *Diff) DeepCopy() *Diff {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnlineSchemaChange.
+func (in *OnlineSchemaChange) DeepCopy() *OnlineSchemaChange {
 	if in == nil {
 		return nil
 	}
-	out := new(Diff)
+	out := new(OnlineSchemaChange)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Dir) DeepCopyInto(out *Dir) {
+func (in *OnlineSchemaChangeStatus) DeepCopyInto(out *OnlineSchemaChangeStatus) {
 	*out = *in
-	if in.ConfigMapRef != nil {
-		in, out := &in.ConfigMapRef, &out.ConfigMapRef
-		*out = new(corev1.LocalObjectReference)
-		**out = **in
-	}
-	out.Remote = in.Remote
-	if in.Local != nil {
-		in, out := &in.Local, &out.Local
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+	if in.Pending != nil {
+		in, out := &in.Pending, &out.Pending
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Dir.
-func (in *Dir) DeepCopy() *Dir {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnlineSchemaChangeStatus.
+func (in *OnlineSchemaChangeStatus) DeepCopy() *OnlineSchemaChangeStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(Dir)
+	out := new(OnlineSchemaChangeStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Lint) DeepCopyInto(out *Lint) {
+func (in *Output) DeepCopyInto(out *Output) {
 	*out = *in
-	if in.Destructive != nil {
-		in, out := &in.Destructive, &out.Destructive
-		*out = new(CheckConfig)
+	if in.Migrations != nil {
+		in, out := &in.Migrations, &out.Migrations
+		*out = new(MigrationsOutput)
 		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Lint.
-func (in *Lint) DeepCopy() *Lint {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Output.
+func (in *Output) DeepCopy() *Output {
 	if in == nil {
 		return nil
 	}
-	out := new(Lint)
+	out := new(Output)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -432,6 +1472,11 @@ func (in *Policy) DeepCopyInto(out *Policy) {
 		*out = new(Diff)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Impact != nil {
+		in, out := &in.Impact, &out.Impact
+		*out = new(Impact)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Policy.
@@ -464,6 +1509,33 @@ func (in *ProtectFlows) DeepCopy() *ProtectFlows {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QueryGenerator) DeepCopyInto(out *QueryGenerator) {
+	*out = *in
+	in.Catalog.DeepCopyInto(&out.Catalog)
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.PasswordFrom.DeepCopyInto(&out.PasswordFrom)
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueryGenerator.
+func (in *QueryGenerator) DeepCopy() *QueryGenerator {
+	if in == nil {
+		return nil
+	}
+	out := new(QueryGenerator)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Remote) DeepCopyInto(out *Remote) {
 	*out = *in
@@ -479,6 +1551,56 @@ func (in *Remote) DeepCopy() *Remote {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Rollout) DeepCopyInto(out *Rollout) {
+	*out = *in
+	if in.Contract != nil {
+		in, out := &in.Contract, &out.Contract
+		*out = new(ContractGate)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rollout.
+func (in *Rollout) DeepCopy() *Rollout {
+	if in == nil {
+		return nil
+	}
+	out := new(Rollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+	if in.Expand != nil {
+		in, out := &in.Expand, &out.Expand
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Contract != nil {
+		in, out := &in.Contract, &out.Contract
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.ExpandedAt.DeepCopyInto(&out.ExpandedAt)
+	if in.ContractedAt != nil {
+		in, out := &in.ContractedAt, &out.ContractedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Schema) DeepCopyInto(out *Schema) {
 	*out = *in
@@ -499,6 +1621,21 @@ func (in *Schema) DeepCopy() *Schema {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchemaPolicyRule) DeepCopyInto(out *SchemaPolicyRule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchemaPolicyRule.
+func (in *SchemaPolicyRule) DeepCopy() *SchemaPolicyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(SchemaPolicyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Secret) DeepCopyInto(out *Secret) {
 	*out = *in
@@ -519,6 +1656,37 @@ func (in *Secret) DeepCopy() *Secret {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretsGenerator) DeepCopyInto(out *SecretsGenerator) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretsGenerator.
+func (in *SecretsGenerator) DeepCopy() *SecretsGenerator {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretsGenerator)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SetTargetStatus) DeepCopyInto(out *SetTargetStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SetTargetStatus.
+func (in *SetTargetStatus) DeepCopy() *SetTargetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SetTargetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SkipChanges) DeepCopyInto(out *SkipChanges) {
 	*out = *in
@@ -534,6 +1702,53 @@ func (in *SkipChanges) DeepCopy() *SkipChanges {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TableImpact) DeepCopyInto(out *TableImpact) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TableImpact.
+func (in *TableImpact) DeepCopy() *TableImpact {
+	if in == nil {
+		return nil
+	}
+	out := new(TableImpact)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetGenerator) DeepCopyInto(out *TargetGenerator) {
+	*out = *in
+	if in.Secrets != nil {
+		in, out := &in.Secrets, &out.Secrets
+		*out = new(SecretsGenerator)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.List != nil {
+		in, out := &in.List, &out.List
+		*out = make([]GeneratedTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Query != nil {
+		in, out := &in.Query, &out.Query
+		*out = new(QueryGenerator)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetGenerator.
+func (in *TargetGenerator) DeepCopy() *TargetGenerator {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetGenerator)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TargetSpec) DeepCopyInto(out *TargetSpec) {
 	*out = *in
@@ -551,6 +1766,101 @@ func (in *TargetSpec) DeepCopy() *TargetSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateMeta) DeepCopyInto(out *TemplateMeta) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateMeta.
+func (in *TemplateMeta) DeepCopy() *TemplateMeta {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateMeta)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Tenants) DeepCopyInto(out *Tenants) {
+	*out = *in
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Tenants.
+func (in *Tenants) DeepCopy() *Tenants {
+	if in == nil {
+		return nil
+	}
+	out := new(Tenants)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Tests) DeepCopyInto(out *Tests) {
+	*out = *in
+	out.ConfigMapRef = in.ConfigMapRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Tests.
+func (in *Tests) DeepCopy() *Tests {
+	if in == nil {
+		return nil
+	}
+	out := new(Tests)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Timeouts) DeepCopyInto(out *Timeouts) {
+	*out = *in
+	if in.Lock != nil {
+		in, out := &in.Lock, &out.Lock
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Statement != nil {
+		in, out := &in.Statement, &out.Statement
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Timeouts.
+func (in *Timeouts) DeepCopy() *Timeouts {
+	if in == nil {
+		return nil
+	}
+	out := new(Timeouts)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TokenFrom) DeepCopyInto(out *TokenFrom) {
 	*out = *in
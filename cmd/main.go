@@ -151,6 +151,31 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "AtlasMigration")
 		os.Exit(1)
 	}
+	if err = controller.NewAtlasMigrationOperationReconciler(mgr, controller.NewAtlasExec, prewarmDevDB).
+		SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AtlasMigrationOperation")
+		os.Exit(1)
+	}
+	if err = controller.NewAtlasSchemaComparisonReconciler(mgr, controller.NewAtlasExec, prewarmDevDB).
+		SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AtlasSchemaComparison")
+		os.Exit(1)
+	}
+	if err = controller.NewAtlasSeedReconciler(mgr, controller.NewAtlasExec, prewarmDevDB).
+		SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AtlasSeed")
+		os.Exit(1)
+	}
+	if err = controller.NewAtlasSchemaSetReconciler(mgr, controller.NewAtlasExec, prewarmDevDB).
+		SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AtlasSchemaSet")
+		os.Exit(1)
+	}
+	if err = controller.NewAtlasMigrationSetReconciler(mgr, controller.NewAtlasExec, prewarmDevDB).
+		SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AtlasMigrationSet")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,98 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// adopt detects the baseline version of an existing database that has
+// no revisions table. It works by comparing the hash of the live schema
+// with the hash of the schema produced by replaying the migration directory
+// up to each available version on the dev database.
+//
+// It returns an empty version if the target database is empty,
+// and an adoptErr if no version or more than one version matches.
+func (r *AtlasMigrationReconciler) adopt(ctx context.Context, cli AtlasExec, data *migrationData, status *atlasexec.MigrateStatus) (string, error) {
+	log := ctrl.Log.WithName("atlas_migration.adopt")
+	hash := func(u string) (string, error) {
+		return cli.SchemaInspect(ctx, &atlasexec.SchemaInspectParams{
+			Env:    data.EnvName,
+			URL:    u,
+			Format: `{{ .Hash | base64url }}`,
+		})
+	}
+	// An empty URL inspects the target database of the env.
+	live, err := hash("")
+	if err != nil {
+		return "", err
+	}
+	// The dev database is clean, use it to detect if the target is empty.
+	empty, err := hash(data.DevURL)
+	if err != nil {
+		return "", err
+	}
+	if live == empty {
+		log.Info("the target database is empty, nothing to adopt")
+		return "", nil
+	}
+	var matches []string
+	for _, f := range status.Available {
+		h, err := hash(fmt.Sprintf("%s?version=%s", data.DirURL(), f.Version))
+		if err != nil {
+			return "", err
+		}
+		if h == live {
+			matches = append(matches, f.Version)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", &adoptErr{
+			reason: "AdoptionMismatch",
+			msg:    "the schema of the target database does not match any version of the migration directory",
+		}
+	case 1:
+		log.Info("adopting the target database", "baseline", matches[0])
+		return matches[0], nil
+	default:
+		return "", &adoptErr{
+			reason: "AdoptionAmbiguous",
+			msg: fmt.Sprintf("the schema of the target database matches multiple versions of the migration directory: %s. "+
+				"Set the `baseline` to one of them", strings.Join(matches, ", ")),
+		}
+	}
+}
+
+// adoptErr is returned when the baseline of an existing database cannot be detected.
+type adoptErr struct {
+	reason string
+	msg    string
+}
+
+// Error implements the error interface
+func (e *adoptErr) Error() string {
+	return e.msg
+}
+
+// Reason returns the reason of the error
+func (e *adoptErr) Reason() string {
+	return e.reason
+}
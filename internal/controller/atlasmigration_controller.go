@@ -23,7 +23,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/url"
+	"slices"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
@@ -59,6 +61,9 @@ type (
 		secretWatcher    *watch.ResourceWatcher
 		recorder         record.EventRecorder
 		devDB            *devDBReconciler
+		// tenantSchemas returns the schemas of the target database,
+		// matched against the pattern of the tenants.
+		tenantSchemas tenantSchemasFn
 	}
 	// migrationData is the data used to render the HCL template
 	// that will be used for Atlas CLI
@@ -71,10 +76,18 @@ type (
 		Cloud           *Cloud
 		RevisionsSchema string
 		Baseline        string
+		Adopt           bool
 		ExecOrder       string
 		MigrateDown     bool
+		RejectEdited    bool
+		Rollback        bool
+		Integrity       dbv1alpha1.DirIntegrity
 		ObservedHash    string
 		RemoteDir       *dbv1alpha1.Remote
+		TestRun         string
+
+		tests    map[string]string
+		timeouts *dbv1alpha1.Timeouts
 	}
 )
 
@@ -88,6 +101,7 @@ func NewAtlasMigrationReconciler(mgr Manager, atlas AtlasExecFn, prewarmDevDB bo
 		secretWatcher:    watch.New(),
 		recorder:         r,
 		devDB:            newDevDB(mgr, r, prewarmDevDB),
+		tenantSchemas:    queryTenantSchemas,
 	}
 }
 
@@ -121,6 +135,15 @@ func (r *AtlasMigrationReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		res.SetNotReady("Reconciling", "Reconciling")
 		return ctrl.Result{Requeue: true}, nil
 	}
+	if res.Spec.Paused {
+		res.SetNotReady("Paused", "Applying migrations is paused, unset `paused` to resume")
+		return ctrl.Result{}, nil
+	}
+	// Resources with tenants apply the migration directory to the
+	// tenant schemas, by the resources created for the tenants.
+	if res.Spec.Tenants != nil {
+		return r.reconcileTenants(ctx, res)
+	}
 	data, err := r.extractData(ctx, res)
 	if err != nil {
 		var reason = "ReadingMigrationData"
@@ -214,6 +237,12 @@ func (r *AtlasMigrationReconciler) watchRefs(res *dbv1alpha1.AtlasMigration) {
 			res.NamespacedName(),
 		)
 	}
+	if t := res.Spec.Tests; t != nil {
+		r.configMapWatcher.Watch(
+			types.NamespacedName{Name: t.ConfigMapRef.Name, Namespace: res.Namespace},
+			res.NamespacedName(),
+		)
+	}
 	if s := res.Spec.Cloud.TokenFrom.SecretKeyRef; s != nil {
 		r.secretWatcher.Watch(
 			types.NamespacedName{Name: s.Name, Namespace: res.Namespace},
@@ -250,6 +279,7 @@ func (r *AtlasMigrationReconciler) reconcile(ctx context.Context, data *migratio
 	wd, err := atlasexec.NewWorkingDir(
 		atlasexec.WithAtlasHCL(data.render),
 		atlasexec.WithMigrations(data.Dir),
+		withTestFiles(data.tests),
 	)
 	if err != nil {
 		res.SetNotReady("ReadingMigrationData", err.Error())
@@ -261,15 +291,99 @@ func (r *AtlasMigrationReconciler) reconcile(ctx context.Context, data *migratio
 		return err
 	}
 	log.Info("reconciling migration", "env", data.EnvName)
+	// Compare the migration directory with the last applied one (if any),
+	// to explain checksum errors and enforce the edit policy.
+	var changes *dirChanges
+	if data.Dir != nil && data.DirLatest != nil {
+		if changes, err = diffDirs(data.DirLatest, data.Dir); err != nil {
+			res.SetNotReady("ReadingMigrationData", err.Error())
+			return err
+		}
+		// Only the edited files are blocked by the policy, out-of-order and
+		// deleted files are left to the checksum validation of Atlas.
+		if data.RejectEdited && len(changes.Edited) > 0 {
+			msg := fmt.Sprintf("applied migration files were edited: %s. Set `policy.rejectEdited` to false to allow it", strings.Join(changes.Edited, ", "))
+			res.SetNotReady("EditedMigrationFiles", msg)
+			return &ProtectedFlowError{reason: "EditedMigrationFiles", msg: msg}
+		}
+	}
+	// Reject edits to the files pinned by the generate-and-lock integrity mode.
+	if data.Integrity == dbv1alpha1.DirIntegrityGenerateAndLock && res.Status.LockedSum != "" {
+		edited, err := lockedEdits(res.Status.LockedSum, data.Dir)
+		if err != nil {
+			res.SetNotReady("ReadingMigrationData", err.Error())
+			return err
+		}
+		if len(edited) > 0 {
+			msg := fmt.Sprintf("applied migration files were edited: %s. The directory integrity is locked, revert the changes or set `dir.integrity` to generate to allow it", strings.Join(edited, ", "))
+			res.SetNotReady("EditedMigrationFiles", msg)
+			return &ProtectedFlowError{reason: "EditedMigrationFiles", msg: msg}
+		}
+	}
 	// Check if there are any pending migration files
 	status, err := c.MigrateStatus(ctx, &atlasexec.MigrateStatusParams{Env: data.EnvName})
 	if err != nil {
-		res.SetNotReady("Migrating", err.Error())
 		if isChecksumErr(err) {
+			if !changes.empty() {
+				err = fmt.Errorf("%w\nThe migration directory differs from the last applied one:\n%s", err, changes)
+			}
+			res.SetNotReady("Migrating", err.Error())
 			return err
 		}
+		res.SetNotReady("Migrating", err.Error())
 		return transient(err)
 	}
+	// Take over the database from the AtlasSchema that manages it, if it has no revisions table.
+	if res.Spec.HandoverFrom != nil && len(status.Applied) == 0 {
+		data.Baseline, err = r.handover(ctx, c, data, res, status)
+		switch e := (*handoverErr)(nil); {
+		case errors.As(err, &e) && e.retry:
+			res.SetNotReady(e.Reason(), e.Error())
+			return transient(err)
+		case errors.As(err, &e):
+			res.SetNotReady(e.Reason(), e.Error())
+			return err
+		case err != nil:
+			res.SetNotReady("Handover", err.Error())
+			return transient(err)
+		}
+		// Render the atlas.hcl again with the baseline,
+		// then refresh the pending migration files.
+		if err = atlasexec.WithAtlasHCL(data.render)(wd); err != nil {
+			res.SetNotReady("Handover", err.Error())
+			return err
+		}
+		if status, err = c.MigrateStatus(ctx, &atlasexec.MigrateStatusParams{Env: data.EnvName}); err != nil {
+			res.SetNotReady("Migrating", err.Error())
+			return transient(err)
+		}
+		r.recorder.Eventf(res, corev1.EventTypeNormal, "HandedOver", "Took over the database from AtlasSchema %q with baseline version %s", res.Spec.HandoverFrom.Name, data.Baseline)
+	}
+	// Adopt the existing database if it has no revisions table.
+	if data.Adopt && data.Baseline == "" && len(status.Applied) == 0 {
+		data.Baseline, err = r.adopt(ctx, c, data, status)
+		switch e := (*adoptErr)(nil); {
+		case errors.As(err, &e):
+			res.SetNotReady(e.Reason(), e.Error())
+			return err
+		case err != nil:
+			res.SetNotReady("Adopting", err.Error())
+			return transient(err)
+		}
+		if data.Baseline != "" {
+			// Render the atlas.hcl again with the detected baseline,
+			// then refresh the pending migration files.
+			if err = atlasexec.WithAtlasHCL(data.render)(wd); err != nil {
+				res.SetNotReady("Adopting", err.Error())
+				return err
+			}
+			if status, err = c.MigrateStatus(ctx, &atlasexec.MigrateStatusParams{Env: data.EnvName}); err != nil {
+				res.SetNotReady("Migrating", err.Error())
+				return transient(err)
+			}
+			r.recorder.Eventf(res, corev1.EventTypeNormal, "Adopted", "Database adopted with baseline version %s", data.Baseline)
+		}
+	}
 	switch {
 	case len(status.Pending) == 0 && len(status.Applied) > 0 && len(status.Available) < len(status.Applied):
 		if !data.MigrateDown {
@@ -354,6 +468,21 @@ func (r *AtlasMigrationReconciler) reconcile(ctx context.Context, data *migratio
 		r.recordApplied(res, status.Current)
 	default:
 		log.Info("applying pending migrations", "count", len(status.Pending))
+		// Run the migration tests against the dev database before touching the target.
+		if len(data.tests) > 0 {
+			_, err = c.MigrateTest(ctx, &atlasexec.MigrateTestParams{
+				Env: data.EnvName,
+				Run: data.TestRun,
+			})
+			if err = testsResult(err); err != nil {
+				reason := "TestingMigrations"
+				if e := (*testsErr)(nil); errors.As(err, &e) {
+					reason = e.Reason()
+				}
+				res.SetNotReady(reason, err.Error())
+				return err
+			}
+		}
 		// There are pending migrations
 		// Execute Atlas CLI migrate command
 		report, err := c.MigrateApply(ctx, &atlasexec.MigrateApplyParams{
@@ -363,10 +492,21 @@ func (r *AtlasMigrationReconciler) reconcile(ctx context.Context, data *migratio
 				TriggerVersion: dbv1alpha1.VersionFromContext(ctx),
 			},
 		})
+		if isLockTimeout(err) {
+			// Nothing was applied by the failed statement, retry with a backoff.
+			res.Status.LockTimeouts++
+			reason, msg := "LockTimeout", fmt.Sprintf("%s\nAttempt %d failed on a lock timeout, retrying", err, res.Status.LockTimeouts)
+			res.SetNotReady(reason, msg)
+			// Reported as a LockTimeout warning, like the lock timeouts of schema changes.
+			return lockTimeoutRetry(&ProtectedFlowError{reason: reason, msg: msg}, res.Status.LockTimeouts)
+		}
 		if err != nil {
 			res.SetNotReady("Migrating", err.Error())
 			if !isSQLErr(err) {
-				err = transient(err)
+				return transient(err)
+			}
+			if data.Rollback {
+				return r.rollback(ctx, c, data, res, status, err)
 			}
 			return err
 		}
@@ -385,6 +525,87 @@ func (r *AtlasMigrationReconciler) reconcile(ctx context.Context, data *migratio
 			return err
 		}
 	}
+	if data.Integrity == dbv1alpha1.DirIntegrityGenerateAndLock {
+		// Pin the checksums of the applied directory.
+		sum, err := data.Dir.Checksum()
+		if err != nil {
+			return err
+		}
+		b, err := sum.MarshalText()
+		if err != nil {
+			return err
+		}
+		res.Status.LockedSum = string(b)
+	}
+	return nil
+}
+
+// lockedEdits returns the files of the locked atlas.sum
+// whose checksum differs in the given directory.
+func lockedEdits(locked string, dir migrate.Dir) ([]string, error) {
+	var prev migrate.HashFile
+	if err := prev.UnmarshalText([]byte(locked)); err != nil {
+		return nil, err
+	}
+	curr, err := dir.Checksum()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string, len(curr))
+	for _, f := range curr {
+		hashes[f.N] = f.H
+	}
+	var edited []string
+	for _, f := range prev {
+		if h, ok := hashes[f.N]; ok && h != f.H {
+			edited = append(edited, f.N)
+		}
+	}
+	return edited, nil
+}
+
+// rollback reverts the database to the version it was at before the failed
+// run. The current migration directory is used, as it contains the applied
+// versions and the failed one. The failure is reported, but the run is not retried.
+func (r *AtlasMigrationReconciler) rollback(ctx context.Context, c AtlasExec, data *migrationData, res *dbv1alpha1.AtlasMigration, status *atlasexec.MigrateStatus, cause error) error {
+	if len(status.Applied) == 0 {
+		res.SetNotReady("Migrating", fmt.Sprintf("%s\nNo version was applied before the run, skipping rollback", cause))
+		return cause
+	}
+	log := ctrl.Log.WithName("atlas_migration.rollback")
+	log.Info("rolling back the failed migration", "version", status.Current)
+	r.recorder.Event(res, corev1.EventTypeWarning, "Migrating", strings.TrimSpace(cause.Error()))
+	run, err := c.MigrateDown(ctx, &atlasexec.MigrateDownParams{
+		Env:       data.EnvName,
+		ToVersion: status.Current,
+		Context: &atlasexec.DeployRunContext{
+			TriggerType:    atlasexec.TriggerTypeKubernetes,
+			TriggerVersion: dbv1alpha1.VersionFromContext(ctx),
+		},
+	})
+	if err != nil {
+		res.SetNotReady("RollbackFailed", fmt.Sprintf("%s\nRollback to version %s failed: %s", cause, status.Current, err))
+		if !isSQLErr(err) {
+			err = transient(err)
+		}
+		return err
+	}
+	switch run.Status {
+	case StatePending:
+		res.SetNotReady("ApprovalPending", fmt.Sprintf("%s\nRollback to version %s is waiting for approval", cause, status.Current))
+		res.Status.ApprovalURL = run.URL
+		return transient(&ProtectedFlowError{
+			reason: "ApprovalPending",
+			msg:    fmt.Sprintf("plan approval pending, review here: %s", run.URL),
+		})
+	case StateAborted:
+		res.SetNotReady("PlanRejected", fmt.Sprintf("%s\nRollback to version %s is aborted", cause, status.Current))
+		res.Status.ApprovalURL = run.URL
+		// Rollback is aborted, no need to reapply
+		return fmt.Errorf("plan rejected, review here: %s", run.URL)
+	}
+	res.SetNotReady("RolledBack", fmt.Sprintf("%s\nRolled back to version %s", cause, status.Current))
+	r.recorder.Eventf(res, corev1.EventTypeNormal, "RolledBack", "Rolled back to version %s", status.Current)
 	return nil
 }
 
@@ -412,6 +633,7 @@ func (r *AtlasMigrationReconciler) extractData(ctx context.Context, res *dbv1alp
 			DevURL:          s.DevURL,
 			RevisionsSchema: s.RevisionsSchema,
 			Baseline:        s.Baseline,
+			Adopt:           s.Adopt,
 			ExecOrder:       string(s.ExecOrder),
 			MigrateDown:     false,
 		}
@@ -419,15 +641,26 @@ func (r *AtlasMigrationReconciler) extractData(ctx context.Context, res *dbv1alp
 	if env := s.EnvName; env != "" {
 		data.EnvName = env
 	}
+	if p := s.Policy; p != nil {
+		data.RejectEdited = p.RejectEdited
+		data.Rollback = p.OnFailure == dbv1alpha1.MigrationFailureRollback
+	}
 	if data.URL, err = s.DatabaseURL(ctx, r, res.Namespace); err != nil {
 		return nil, transient(err)
 	}
+	if msg := ignoredTimeouts(data.URL, s.Timeouts); msg != "" {
+		r.recorder.Event(res, corev1.EventTypeWarning, "TimeoutsIgnored", msg)
+	}
+	data.timeouts = s.Timeouts
 	switch d := s.Dir; {
 	case d.Remote.Name != "":
 		c := s.Cloud
 		if c.TokenFrom.SecretKeyRef == nil {
 			return nil, errors.New("cannot use remote directory without Atlas Cloud token")
 		}
+		if s.Adopt {
+			return nil, errors.New("cannot adopt a database using a remote directory, set the `baseline` instead")
+		}
 		if f := s.ProtectedFlows; f != nil {
 			if d := f.MigrateDown; d != nil {
 				if d.Allow && d.AutoApprove {
@@ -471,6 +704,16 @@ func (r *AtlasMigrationReconciler) extractData(ctx context.Context, res *dbv1alp
 		if err != nil {
 			return nil, err
 		}
+		if data.Integrity = d.Integrity; data.Integrity != "" && data.Integrity != dbv1alpha1.DirIntegrityRequire {
+			// Generate the atlas.sum file instead of requiring it in the directory.
+			sum, err := data.Dir.Checksum()
+			if err != nil {
+				return nil, err
+			}
+			if err = migrate.WriteSumFile(data.Dir, sum); err != nil {
+				return nil, err
+			}
+		}
 		data.DirLatest, err = r.readDirState(ctx, res)
 		if err != nil {
 			return nil, err
@@ -478,6 +721,9 @@ func (r *AtlasMigrationReconciler) extractData(ctx context.Context, res *dbv1alp
 	default:
 		return nil, errors.New("no directory specified")
 	}
+	if data.Rollback && (data.RemoteDir != nil || !data.MigrateDown) {
+		return nil, &ProtectedFlowError{"ProtectedFlowError", "`onFailure: rollback` requires a local migration directory and `protectedFlows.migrateDown.allow`"}
+	}
 	if s := s.DevURLFrom.SecretKeyRef; s != nil {
 		// SecretKeyRef is set, get the secret value
 		// then override the dev url.
@@ -486,6 +732,12 @@ func (r *AtlasMigrationReconciler) extractData(ctx context.Context, res *dbv1alp
 			return nil, err
 		}
 	}
+	if t := s.Tests; t != nil {
+		if data.tests, err = testFiles(ctx, r, res.Namespace, t); err != nil {
+			return nil, err
+		}
+		data.TestRun = t.Run
+	}
 	data.ObservedHash, err = hashMigrationData(data)
 	if err != nil {
 		return nil, err
@@ -499,7 +751,7 @@ func (r *AtlasMigrationReconciler) recordApplied(res *dbv1alpha1.AtlasMigration,
 
 func (r *AtlasMigrationReconciler) recordErrEvent(res *dbv1alpha1.AtlasMigration, err error) {
 	reason := "Error"
-	switch e := (&ProtectedFlowError{}); {
+	switch e := (interface{ Reason() string })(nil); {
 	case errors.As(err, &e):
 		reason = e.Reason()
 	case isTransient(err):
@@ -532,6 +784,17 @@ func hashMigrationData(d *migrationData) (string, error) {
 	default:
 		return "", errors.New("migration data is empty")
 	}
+	if len(d.tests) > 0 {
+		h.Write([]byte(d.TestRun))
+		for _, k := range slices.Sorted(maps.Keys(d.tests)) {
+			h.Write([]byte(k))
+			h.Write([]byte(d.tests[k]))
+		}
+	}
+	if t := d.timeouts; t != nil {
+		u, _ := sessionURL(d.URL, t)
+		h.Write([]byte(u.String()))
+	}
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
@@ -542,6 +805,18 @@ func (d *migrationData) DirURL() string {
 	return "file://migrations"
 }
 
+// TargetURL returns the URL of the target database with its session
+// settings, used by the atlas.hcl template.
+func (d *migrationData) TargetURL() *url.URL {
+	u, _ := sessionURL(d.URL, d.timeouts)
+	return u
+}
+
+// TestSrc returns the paths of the test files, used by the atlas.hcl template.
+func (d *migrationData) TestSrc() []string {
+	return testSrc(d.tests)
+}
+
 // render renders the atlas.hcl template.
 //
 // The template is used by the Atlas CLI to apply the migrations directory.
@@ -614,6 +889,72 @@ func newSecretObject(obj client.Object, dir migrate.Dir, labels map[string]strin
 	}, nil
 }
 
+// dirChanges describes how a migration directory differs
+// from the last one applied to the target database.
+type dirChanges struct {
+	Edited     []string // Applied files whose content has changed.
+	OutOfOrder []string // New files with a version lower than the last applied one.
+	Deleted    []string // Applied files that no longer exist.
+}
+
+// diffDirs compares the last applied migration directory with the current one.
+func diffDirs(latest, current migrate.Dir) (*dirChanges, error) {
+	applied, err := latest.Files()
+	if err != nil {
+		return nil, err
+	}
+	files, err := current.Files()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		last    string
+		c       = &dirChanges{}
+		byNames = make(map[string]migrate.File, len(applied))
+	)
+	for _, f := range applied {
+		byNames[f.Name()] = f
+		if v := f.Version(); v > last {
+			last = v
+		}
+	}
+	for _, f := range files {
+		switch a, ok := byNames[f.Name()]; {
+		case ok && !bytes.Equal(a.Bytes(), f.Bytes()):
+			c.Edited = append(c.Edited, f.Name())
+		case !ok && f.Version() < last:
+			c.OutOfOrder = append(c.OutOfOrder, f.Name())
+		}
+		delete(byNames, f.Name())
+	}
+	for _, f := range applied {
+		if _, ok := byNames[f.Name()]; ok {
+			c.Deleted = append(c.Deleted, f.Name())
+		}
+	}
+	return c, nil
+}
+
+// empty reports if there are no changes.
+func (c *dirChanges) empty() bool {
+	return c == nil || len(c.Edited)+len(c.OutOfOrder)+len(c.Deleted) == 0
+}
+
+// String implements the fmt.Stringer interface.
+func (c *dirChanges) String() string {
+	var b strings.Builder
+	for _, f := range c.Edited {
+		fmt.Fprintf(&b, "- edited: %s\n", f)
+	}
+	for _, f := range c.OutOfOrder {
+		fmt.Fprintf(&b, "- added out of order: %s\n", f)
+	}
+	for _, f := range c.Deleted {
+		fmt.Fprintf(&b, "- deleted: %s\n", f)
+	}
+	return b.String()
+}
+
 func extractDirFromSecret(sec *corev1.Secret) (migrate.Dir, error) {
 	if sec.Type != "atlasgo.io/db.v1" {
 		return nil, fmt.Errorf("invalid secret type, got %q", sec.Type)
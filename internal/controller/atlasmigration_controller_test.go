@@ -19,6 +19,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -499,6 +500,318 @@ func TestMigration_MigrateDown_Local(t *testing.T) {
 	}, h.events())
 }
 
+func TestMigration_ChecksumMismatch(t *testing.T) {
+	var (
+		meta = migrationObjmeta()
+		obj  = &dbv1alpha1.AtlasMigration{
+			ObjectMeta: meta,
+			Spec: dbv1alpha1.AtlasMigrationSpec{
+				TargetSpec: dbv1alpha1.TargetSpec{
+					URL: "sqlite://file?mode=memory",
+				},
+				Dir: dbv1alpha1.Dir{
+					Local: map[string]string{
+						"1.sql":     "CREATE TABLE t1 (id INT, c INT);",
+						"0.sql":     "CREATE TABLE t0 (id INT);",
+						"atlas.sum": "invalid",
+					},
+				},
+			},
+			Status: dbv1alpha1.AtlasMigrationStatus{
+				Conditions: []metav1.Condition{
+					{Type: "Ready", Status: metav1.ConditionFalse},
+				},
+			},
+		}
+	)
+	latestDir := must(memDir(map[string]string{
+		"1.sql": "CREATE TABLE t1 (id INT);",
+		"2.sql": "CREATE TABLE t2 (id INT);",
+	}))
+	mockExec := &mockAtlasExec{}
+	mockExec.status.err = errors.New("Error: checksum mismatch")
+	h, reconcile := newRunner(NewAtlasMigrationReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(obj)
+		cb.WithObjects(obj, must(newSecretObject(obj, latestDir, nil)))
+	}, mockExec)
+	assert := func(reason, msg string) {
+		t.Helper()
+		reconcile(obj, func(result ctrl.Result, err error) {
+			require.NoError(t, err)
+			require.EqualValues(t, ctrl.Result{}, result)
+			res := &dbv1alpha1.AtlasMigration{ObjectMeta: meta}
+			h.get(t, res)
+			require.False(t, res.IsReady())
+			require.Equal(t, reason, res.Status.Conditions[0].Reason)
+			require.Equal(t, msg, res.Status.Conditions[0].Message)
+		})
+	}
+	// The condition explains the checksum error.
+	assert("Migrating", "Error: checksum mismatch\n"+
+		"The migration directory differs from the last applied one:\n"+
+		"- edited: 1.sql\n"+
+		"- added out of order: 0.sql\n"+
+		"- deleted: 2.sql\n")
+	// Reject the edits of applied files.
+	h.patch(t, &dbv1alpha1.AtlasMigration{
+		ObjectMeta: meta,
+		Spec: dbv1alpha1.AtlasMigrationSpec{
+			Policy: &dbv1alpha1.MigrationPolicy{RejectEdited: true},
+		},
+	})
+	assert("EditedMigrationFiles", "applied migration files were edited: 1.sql. Set `policy.rejectEdited` to false to allow it")
+	require.Equal(t, []string{
+		"Warning Error Error: checksum mismatch\n" +
+			"The migration directory differs from the last applied one:\n" +
+			"- edited: 1.sql\n" +
+			"- added out of order: 0.sql\n" +
+			"- deleted: 2.sql",
+		"Warning EditedMigrationFiles applied migration files were edited: 1.sql. Set `policy.rejectEdited` to false to allow it",
+	}, h.events())
+}
+
+func TestMigration_Adopt(t *testing.T) {
+	var (
+		meta = migrationObjmeta()
+		obj  = &dbv1alpha1.AtlasMigration{
+			ObjectMeta: meta,
+			Spec: dbv1alpha1.AtlasMigrationSpec{
+				TargetSpec: dbv1alpha1.TargetSpec{
+					URL: "sqlite://file?mode=memory",
+				},
+				Adopt: true,
+				Dir: dbv1alpha1.Dir{
+					Local: map[string]string{
+						"1.sql": "CREATE TABLE t1 (id INT);",
+						"2.sql": "CREATE TABLE t2 (id INT);",
+						"3.sql": "CREATE TABLE t3 (id INT);",
+					},
+				},
+			},
+			Status: dbv1alpha1.AtlasMigrationStatus{
+				Conditions: []metav1.Condition{
+					{Type: "Ready", Status: metav1.ConditionFalse},
+				},
+			},
+		}
+		hashes = map[string]string{
+			"":                            "live",
+			"sqlite://db?mode=memory":     "empty",
+			"file://migrations?version=1": "v1",
+			"file://migrations?version=2": "live",
+			"file://migrations?version=3": "v3",
+		}
+	)
+	mockExec := &mockAtlasExec{}
+	mockExec.inspectFn = func(p *atlasexec.SchemaInspectParams) (string, error) {
+		return hashes[p.URL], nil
+	}
+	mockExec.status.res = &atlasexec.MigrateStatus{
+		Available: []atlasexec.File{{Version: "1"}, {Version: "2"}, {Version: "3"}},
+		Pending:   []atlasexec.File{{Version: "3"}},
+	}
+	mockExec.apply.res = &atlasexec.MigrateApply{Target: "3"}
+	h, reconcile := newRunner(NewAtlasMigrationReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(obj)
+		cb.WithObjects(obj)
+	}, mockExec)
+	assert := func(ready bool, reason, msg string) {
+		t.Helper()
+		reconcile(obj, func(result ctrl.Result, err error) {
+			require.NoError(t, err)
+			require.EqualValues(t, ctrl.Result{}, result)
+			res := &dbv1alpha1.AtlasMigration{ObjectMeta: meta}
+			h.get(t, res)
+			require.Equal(t, ready, res.IsReady())
+			require.Equal(t, reason, res.Status.Conditions[0].Reason)
+			require.Contains(t, res.Status.Conditions[0].Message, msg)
+		})
+	}
+	// The schema matches the second version.
+	assert(true, "Applied", "")
+	// The schema matches multiple versions.
+	hashes["file://migrations?version=3"] = "live"
+	h.patch(t, &dbv1alpha1.AtlasMigration{
+		ObjectMeta: meta,
+		Status: dbv1alpha1.AtlasMigrationStatus{
+			Conditions: []metav1.Condition{
+				{Type: "Ready", Status: metav1.ConditionFalse},
+			},
+		},
+	})
+	assert(false, "AdoptionAmbiguous", "matches multiple versions of the migration directory: 2, 3")
+	// The schema does not match any version.
+	delete(hashes, "file://migrations?version=2")
+	delete(hashes, "file://migrations?version=3")
+	assert(false, "AdoptionMismatch", "does not match any version")
+	require.Equal(t, []string{
+		"Normal Adopted Database adopted with baseline version 2",
+		"Normal Applied Version 3 applied",
+		"Warning AdoptionAmbiguous the schema of the target database matches multiple versions of the migration directory: 2, 3. Set the `baseline` to one of them",
+		"Warning AdoptionMismatch the schema of the target database does not match any version of the migration directory",
+	}, h.events())
+}
+
+func TestMigration_Rollback(t *testing.T) {
+	var (
+		meta = migrationObjmeta()
+		obj  = &dbv1alpha1.AtlasMigration{
+			ObjectMeta: meta,
+			Spec: dbv1alpha1.AtlasMigrationSpec{
+				TargetSpec: dbv1alpha1.TargetSpec{
+					URL: "sqlite://file?mode=memory",
+				},
+				Dir: dbv1alpha1.Dir{
+					Local: map[string]string{
+						"1.sql": "CREATE TABLE t1 (id INT);",
+						"2.sql": "CREATE TABLE t2 (id INT);\nCREAT TABLE t3 (id INT);",
+					},
+				},
+				Policy: &dbv1alpha1.MigrationPolicy{
+					OnFailure: dbv1alpha1.MigrationFailureRollback,
+				},
+			},
+			Status: dbv1alpha1.AtlasMigrationStatus{
+				Conditions: []metav1.Condition{
+					{Type: "Ready", Status: metav1.ConditionFalse},
+				},
+			},
+		}
+	)
+	mockExec := &mockAtlasExec{}
+	mockExec.status.res = &atlasexec.MigrateStatus{
+		Current:   "1",
+		Available: []atlasexec.File{{Version: "1"}, {Version: "2"}},
+		Applied:   []*atlasexec.Revision{{Version: "1"}},
+		Pending:   []atlasexec.File{{Version: "2"}},
+	}
+	mockExec.apply.err = errors.New(`sql/migrate: executing statement "CREAT TABLE t3 (id INT);" from version "2": near "CREAT": syntax error`)
+	mockExec.down.res = &atlasexec.MigrateDown{Status: StateApplied, Target: "1"}
+	h, reconcile := newRunner(NewAtlasMigrationReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(obj)
+		cb.WithObjects(obj)
+	}, mockExec)
+	assert := func(except ctrl.Result, reason, msg string) {
+		t.Helper()
+		reconcile(obj, func(result ctrl.Result, err error) {
+			require.NoError(t, err)
+			require.EqualValues(t, except, result)
+			res := &dbv1alpha1.AtlasMigration{ObjectMeta: meta}
+			h.get(t, res)
+			require.False(t, res.IsReady())
+			require.Equal(t, reason, res.Status.Conditions[0].Reason)
+			require.Contains(t, res.Status.Conditions[0].Message, msg)
+		})
+	}
+	// Rollback requires the migrate-down flow.
+	assert(ctrl.Result{}, "ProtectedFlowError", "`onFailure: rollback` requires a local migration directory")
+	h.patch(t, &dbv1alpha1.AtlasMigration{
+		ObjectMeta: meta,
+		Spec: dbv1alpha1.AtlasMigrationSpec{
+			ProtectedFlows: &dbv1alpha1.ProtectFlows{
+				MigrateDown: &dbv1alpha1.DeploymentFlow{Allow: true, AutoApprove: true},
+			},
+		},
+	})
+	assert(ctrl.Result{}, "RolledBack", "Rolled back to version 1")
+	// The current directory, that contains the failed version, is used.
+	require.Equal(t, "1", mockExec.downParams.ToVersion)
+	require.Empty(t, mockExec.downParams.DirURL)
+	// The rollback waits for approval.
+	mockExec.down.res = &atlasexec.MigrateDown{Status: StatePending, URL: "https://atlas.example/plan/1"}
+	assert(ctrl.Result{RequeueAfter: 5 * time.Second}, "ApprovalPending", "Rollback to version 1 is waiting for approval")
+	res := &dbv1alpha1.AtlasMigration{ObjectMeta: meta}
+	h.get(t, res)
+	require.Equal(t, "https://atlas.example/plan/1", res.Status.ApprovalURL)
+	// The rollback is rejected.
+	mockExec.down.res = &atlasexec.MigrateDown{Status: StateAborted, URL: "https://atlas.example/plan/1"}
+	assert(ctrl.Result{}, "PlanRejected", "Rollback to version 1 is aborted")
+	// The rollback itself fails.
+	mockExec.down.err = errors.New("connection refused")
+	assert(ctrl.Result{RequeueAfter: 5 * time.Second}, "RollbackFailed", "Rollback to version 1 failed: connection refused")
+	require.Equal(t, []string{
+		"Warning ProtectedFlowError `onFailure: rollback` requires a local migration directory and `protectedFlows.migrateDown.allow`",
+		"Warning Migrating " + mockExec.apply.err.Error(),
+		"Normal RolledBack Rolled back to version 1",
+		"Warning Migrating " + mockExec.apply.err.Error(),
+		"Warning ApprovalPending plan approval pending, review here: https://atlas.example/plan/1",
+		"Warning Migrating " + mockExec.apply.err.Error(),
+		"Warning Error plan rejected, review here: https://atlas.example/plan/1",
+		"Warning Migrating " + mockExec.apply.err.Error(),
+		"Warning TransientErr connection refused",
+	}, h.events())
+}
+
+func TestMigration_DirIntegrity(t *testing.T) {
+	var (
+		meta = migrationObjmeta()
+		obj  = &dbv1alpha1.AtlasMigration{
+			ObjectMeta: meta,
+			Spec: dbv1alpha1.AtlasMigrationSpec{
+				TargetSpec: dbv1alpha1.TargetSpec{
+					URL: "sqlite://file?mode=memory",
+				},
+				Dir: dbv1alpha1.Dir{
+					Local: map[string]string{
+						"1.sql": "CREATE TABLE t1 (id INT);",
+					},
+					Integrity: dbv1alpha1.DirIntegrityGenerateAndLock,
+				},
+			},
+			Status: dbv1alpha1.AtlasMigrationStatus{
+				Conditions: []metav1.Condition{
+					{Type: "Ready", Status: metav1.ConditionFalse},
+				},
+			},
+		}
+	)
+	mockExec := &mockAtlasExec{}
+	mockExec.status.res = &atlasexec.MigrateStatus{
+		Current:   "1",
+		Available: []atlasexec.File{{Version: "1"}},
+		Applied:   []*atlasexec.Revision{{Version: "1"}},
+	}
+	h, reconcile := newRunner(NewAtlasMigrationReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(obj)
+		cb.WithObjects(obj)
+	}, mockExec)
+	// The atlas.sum file is generated in memory.
+	r := &AtlasMigrationReconciler{Client: h.client}
+	data, err := r.extractData(context.Background(), obj)
+	require.NoError(t, err)
+	require.NoError(t, migrate.Validate(data.Dir))
+	res := &dbv1alpha1.AtlasMigration{ObjectMeta: meta}
+	reconcile(obj, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+		h.get(t, res)
+		require.True(t, res.IsReady())
+		require.Equal(t, string(must(must(data.Dir.Checksum()).MarshalText())), res.Status.LockedSum)
+	})
+	// Edits to the applied files are rejected.
+	h.patch(t, &dbv1alpha1.AtlasMigration{
+		ObjectMeta: meta,
+		Spec: dbv1alpha1.AtlasMigrationSpec{
+			Dir: dbv1alpha1.Dir{
+				Local: map[string]string{
+					"1.sql": "CREATE TABLE t1 (id INT, c INT);",
+				},
+			},
+		},
+	})
+	// The first reconcile detects the change.
+	reconcile(obj, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+		require.EqualValues(t, ctrl.Result{Requeue: true}, result)
+	})
+	reconcile(obj, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+		h.get(t, res)
+		require.False(t, res.IsReady())
+		require.Equal(t, "EditedMigrationFiles", res.Status.Conditions[0].Reason)
+		require.Contains(t, res.Status.Conditions[0].Message, "applied migration files were edited: 1.sql")
+	})
+}
+
 func TestReconcile_Diff(t *testing.T) {
 	tt := migrationCliTest(t)
 	tt.initDefaultAtlasMigration()
@@ -1291,3 +1604,30 @@ func writeDir(t *testing.T, dir migrate.Dir, w io.Writer) {
 	_, err = fmt.Fprintf(w, `{"data":{"dirState":{"content":%q}}}`, base64.StdEncoding.EncodeToString(arc))
 	require.NoError(t, err)
 }
+
+func TestMigration_Paused(t *testing.T) {
+	mig := &dbv1alpha1.AtlasMigration{
+		ObjectMeta: migrationObjmeta(),
+		Spec: dbv1alpha1.AtlasMigrationSpec{
+			TargetSpec: dbv1alpha1.TargetSpec{URL: "sqlite://file?mode=memory"},
+			Dir:        dbv1alpha1.Dir{Local: map[string]string{"1.sql": "CREATE TABLE t1 (id INT);"}},
+			Paused:     true,
+		},
+		Status: dbv1alpha1.AtlasMigrationStatus{
+			Conditions: []metav1.Condition{{Type: "Ready", Status: metav1.ConditionFalse}},
+		},
+	}
+	mockExec := &mockAtlasExec{}
+	h, reconcile := newRunner(NewAtlasMigrationReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(mig)
+		cb.WithObjects(mig)
+	}, mockExec)
+	reconcile(mig, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+		require.EqualValues(t, ctrl.Result{}, result)
+	})
+	res := &dbv1alpha1.AtlasMigration{ObjectMeta: mig.ObjectMeta}
+	h.get(t, res)
+	require.Equal(t, "Paused", res.Status.Conditions[0].Reason)
+	require.Nil(t, mockExec.status.res)
+}
@@ -0,0 +1,293 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	"ariga.io/atlas/sql/migrate"
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+//+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasmigrationoperations,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasmigrationoperations/finalizers,verbs=update
+//+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasmigrationoperations/status,verbs=get;update;patch
+
+type (
+	// AtlasMigrationOperationReconciler reconciles a AtlasMigrationOperation object
+	AtlasMigrationOperationReconciler struct {
+		client.Client
+		scheme      *runtime.Scheme
+		atlasClient AtlasExecFn
+		recorder    record.EventRecorder
+		devDB       *devDBReconciler
+		// migrations is used to resolve the data of
+		// the referenced AtlasMigration resources.
+		migrations *AtlasMigrationReconciler
+	}
+)
+
+func NewAtlasMigrationOperationReconciler(mgr Manager, atlas AtlasExecFn, prewarmDevDB bool) *AtlasMigrationOperationReconciler {
+	r := mgr.GetEventRecorderFor("atlasmigrationoperation-controller")
+	return &AtlasMigrationOperationReconciler{
+		Client:      mgr.GetClient(),
+		scheme:      mgr.GetScheme(),
+		atlasClient: atlas,
+		recorder:    r,
+		devDB:       newDevDB(mgr, r, prewarmDevDB),
+		migrations: &AtlasMigrationReconciler{
+			Client: mgr.GetClient(),
+			scheme: mgr.GetScheme(),
+		},
+	}
+}
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *AtlasMigrationOperationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	var (
+		log = ctrl.LoggerFrom(ctx)
+		res = &dbv1alpha1.AtlasMigrationOperation{}
+	)
+	if err = r.Get(ctx, req.NamespacedName, res); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	// Operations are never re-run once completed.
+	if res.IsReady() {
+		return ctrl.Result{}, nil
+	}
+	defer func() {
+		if err := r.Status().Update(ctx, res); err != nil {
+			log.Error(err, "failed to update resource status")
+		}
+	}()
+	// When the resource is first created, create the "Ready" condition.
+	if len(res.Status.Conditions) == 0 {
+		res.SetNotReady("Reconciling", "Reconciling")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	mig := &dbv1alpha1.AtlasMigration{}
+	if err = r.Get(ctx, res.MigrationName(), mig); err != nil {
+		res.SetNotReady("ReadingMigration", err.Error())
+		r.recordErrEvent(res, err)
+		return result(transient(err))
+	}
+	data, err := r.migrations.extractData(ctx, mig)
+	if err != nil {
+		res.SetNotReady("ReadingMigrationData", err.Error())
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	if res.Spec.Type == dbv1alpha1.MigrationOperationDown {
+		if err := checkDown(mig, data); err != nil {
+			res.SetNotReady(err.Reason(), err.Error())
+			r.recordErrEvent(res, err)
+			return result(err)
+		}
+	}
+	if res.Spec.Type == dbv1alpha1.MigrationOperationDown && data.DevURL == "" {
+		// Reverting migrations requires a dev-db to compute the down plan.
+		data.DevURL, err = r.devDB.devURL(ctx, mig, *data.URL)
+		if err != nil {
+			res.SetNotReady("GettingDevDB", err.Error())
+			r.recordErrEvent(res, err)
+			return result(err)
+		}
+		defer r.devDB.cleanUp(ctx, mig)
+	}
+	if err = r.run(ctx, mig, data, res); err != nil {
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	r.recorder.Eventf(res, corev1.EventTypeNormal, "Completed", "Operation %s completed: %s", res.Spec.Type, res.Status.Result)
+	return ctrl.Result{}, nil
+}
+
+// run executes the operation against the target database of the migration.
+func (r *AtlasMigrationOperationReconciler) run(ctx context.Context, mig *dbv1alpha1.AtlasMigration, data *migrationData, res *dbv1alpha1.AtlasMigrationOperation) error {
+	s := res.Spec
+	if s.Type == dbv1alpha1.MigrationOperationRehash {
+		return r.rehash(ctx, mig, data, res)
+	}
+	wd, err := atlasexec.NewWorkingDir(
+		atlasexec.WithAtlasHCL(data.render),
+		atlasexec.WithMigrations(data.Dir),
+	)
+	if err != nil {
+		res.SetNotReady("ReadingMigrationData", err.Error())
+		return err
+	}
+	defer wd.Close()
+	c, err := r.atlasClient(wd.Path(), data.Cloud)
+	if err != nil {
+		res.SetNotReady("CreatingAtlasClient", err.Error())
+		return err
+	}
+	switch s.Type {
+	case dbv1alpha1.MigrationOperationResolve:
+		status, err := c.MigrateStatus(ctx, &atlasexec.MigrateStatusParams{Env: data.EnvName})
+		if err != nil {
+			res.SetNotReady("Operating", err.Error())
+			return transient(err)
+		}
+		if status.Error == "" {
+			err = fmt.Errorf("no failed migration to resolve, current version is %s", status.Current)
+			res.SetNotReady("NothingToResolve", err.Error())
+			return err
+		}
+		fallthrough
+	case dbv1alpha1.MigrationOperationSet:
+		if err = c.MigrateSet(ctx, &MigrateSetParams{Env: data.EnvName, Version: s.Version}); err != nil {
+			res.SetNotReady("Operating", err.Error())
+			return err
+		}
+		res.SetReady(dbv1alpha1.AtlasMigrationOperationStatus{
+			Result:         fmt.Sprintf("Revisions table set to version %s", s.Version),
+			CurrentVersion: s.Version,
+			CompletedAt:    time.Now().Unix(),
+		})
+	case dbv1alpha1.MigrationOperationDown:
+		params := &atlasexec.MigrateDownParams{
+			Env:    data.EnvName,
+			Amount: s.Amount,
+			Context: &atlasexec.DeployRunContext{
+				TriggerType:    atlasexec.TriggerTypeKubernetes,
+				TriggerVersion: dbv1alpha1.VersionFromContext(ctx),
+			},
+		}
+		if data.hasRemoteDir() {
+			params.DirURL = fmt.Sprintf("atlas://%s", data.RemoteDir.Name)
+		}
+		run, err := c.MigrateDown(ctx, params)
+		if err != nil {
+			res.SetNotReady("Operating", err.Error())
+			if !isSQLErr(err) {
+				err = transient(err)
+			}
+			return err
+		}
+		switch run.Status {
+		case StatePending:
+			res.SetNotReady("ApprovalPending", fmt.Sprintf("plan approval pending, review here: %s", run.URL))
+			return transient(&ProtectedFlowError{
+				reason: "ApprovalPending",
+				msg:    fmt.Sprintf("plan approval pending, review here: %s", run.URL),
+			})
+		case StateAborted:
+			err = fmt.Errorf("plan rejected, review here: %s", run.URL)
+			res.SetNotReady("PlanRejected", err.Error())
+			return err
+		}
+		res.SetReady(dbv1alpha1.AtlasMigrationOperationStatus{
+			Result:         fmt.Sprintf("Reverted %d migrations from version %s to %s", len(run.Reverted), run.Current, run.Target),
+			CurrentVersion: run.Target,
+			CompletedAt:    time.Now().Unix(),
+		})
+	default:
+		err = fmt.Errorf("unsupported operation type %q", s.Type)
+		res.SetNotReady("UnsupportedOperation", err.Error())
+		return err
+	}
+	return nil
+}
+
+// checkDown reports if the migrations of the AtlasMigration can be reverted. Reverting
+// is gated by the migrate-down protected flow of the AtlasMigration, like its own downgrades,
+// and requires the AtlasMigration to be paused, or it re-applies the reverted migrations.
+func checkDown(mig *dbv1alpha1.AtlasMigration, data *migrationData) *ProtectedFlowError {
+	switch {
+	case !data.MigrateDown:
+		return &ProtectedFlowError{
+			reason: "ProtectedFlowError",
+			msg:    fmt.Sprintf("migrate down is not allowed, set `protectedFlows.migrateDown.allow` to true on AtlasMigration %q to allow it", mig.Name),
+		}
+	case !mig.Spec.Paused:
+		return &ProtectedFlowError{
+			reason: "MigrationNotPaused",
+			msg:    fmt.Sprintf("AtlasMigration %q must be paused to revert its migrations, or it re-applies them", mig.Name),
+		}
+	}
+	return nil
+}
+
+// rehash re-computes the atlas.sum file of the migration directory
+// and writes it back to the ConfigMap the directory is read from.
+func (r *AtlasMigrationOperationReconciler) rehash(ctx context.Context, mig *dbv1alpha1.AtlasMigration, data *migrationData, res *dbv1alpha1.AtlasMigrationOperation) error {
+	ref := mig.Spec.Dir.ConfigMapRef
+	if ref == nil || data.Dir == nil {
+		err := errors.New("rehash is only supported for migration directories read from a ConfigMap")
+		res.SetNotReady("UnsupportedOperation", err.Error())
+		return err
+	}
+	sum, err := data.Dir.Checksum()
+	if err != nil {
+		res.SetNotReady("Operating", err.Error())
+		return err
+	}
+	b, err := sum.MarshalText()
+	if err != nil {
+		res.SetNotReady("Operating", err.Error())
+		return err
+	}
+	cm, err := getConfigMap(ctx, r, res.Namespace, ref)
+	if err != nil {
+		res.SetNotReady("Operating", err.Error())
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = make(map[string]string, 1)
+	}
+	cm.Data[migrate.HashFileName] = string(b)
+	if err = r.Update(ctx, cm); err != nil {
+		res.SetNotReady("Operating", err.Error())
+		return transient(err)
+	}
+	res.SetReady(dbv1alpha1.AtlasMigrationOperationStatus{
+		Result:      fmt.Sprintf("Updated %s of configmap %s with %d files", migrate.HashFileName, ref.Name, len(sum)),
+		CompletedAt: time.Now().Unix(),
+	})
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AtlasMigrationOperationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dbv1alpha1.AtlasMigrationOperation{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
+
+func (r *AtlasMigrationOperationReconciler) recordErrEvent(res *dbv1alpha1.AtlasMigrationOperation, err error) {
+	reason := "Error"
+	switch e := (interface{ Reason() string })(nil); {
+	case errors.As(err, &e):
+		reason = e.Reason()
+	case isTransient(err):
+		reason = "TransientErr"
+	}
+	r.recorder.Event(res, corev1.EventTypeWarning, reason, strings.TrimSpace(err.Error()))
+}
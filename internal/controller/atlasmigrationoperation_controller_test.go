@@ -0,0 +1,274 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	"ariga.io/atlas/sql/migrate"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+func TestMigrationOperation_Set(t *testing.T) {
+	var (
+		mig = &dbv1alpha1.AtlasMigration{
+			ObjectMeta: migrationObjmeta(),
+			Spec: dbv1alpha1.AtlasMigrationSpec{
+				TargetSpec: dbv1alpha1.TargetSpec{
+					URL: "sqlite://file?mode=memory",
+				},
+				Dir: dbv1alpha1.Dir{
+					Local: map[string]string{
+						"1.sql": "CREATE TABLE t1 (id INT);",
+						"2.sql": "CREATE TABLE t2 (id INT);",
+					},
+				},
+			},
+		}
+		op = &dbv1alpha1.AtlasMigrationOperation{
+			ObjectMeta: metav1.ObjectMeta{Name: "set", Namespace: "default"},
+			Spec: dbv1alpha1.AtlasMigrationOperationSpec{
+				MigrationRef: corev1.LocalObjectReference{Name: mig.Name},
+				Type:         dbv1alpha1.MigrationOperationSet,
+				Version:      "1",
+			},
+		}
+	)
+	mockExec := &mockAtlasExec{}
+	h, reconcile := newRunner(NewAtlasMigrationOperationReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(op)
+		cb.WithObjects(mig, op)
+	}, mockExec)
+	// First reconcile creates the "Ready" condition.
+	reconcile(op, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+		require.EqualValues(t, ctrl.Result{Requeue: true}, result)
+	})
+	reconcile(op, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+		require.EqualValues(t, ctrl.Result{}, result)
+	})
+	res := &dbv1alpha1.AtlasMigrationOperation{ObjectMeta: op.ObjectMeta}
+	h.get(t, res)
+	require.True(t, res.IsReady())
+	require.Equal(t, "1", res.Status.CurrentVersion)
+	require.NotZero(t, res.Status.CompletedAt)
+	require.Equal(t, &MigrateSetParams{Env: "kubernetes", Version: "1"}, mockExec.set.res)
+	// Completed operations are not re-run.
+	mockExec.set.res = nil
+	reconcile(op, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+	})
+	require.Nil(t, mockExec.set.res)
+	require.Equal(t, []string{
+		"Normal Completed Operation set completed: Revisions table set to version 1",
+	}, h.events())
+}
+
+func TestMigrationOperation_Resolve(t *testing.T) {
+	var (
+		mig = &dbv1alpha1.AtlasMigration{
+			ObjectMeta: migrationObjmeta(),
+			Spec: dbv1alpha1.AtlasMigrationSpec{
+				TargetSpec: dbv1alpha1.TargetSpec{
+					URL: "sqlite://file?mode=memory",
+				},
+				Dir: dbv1alpha1.Dir{
+					Local: map[string]string{
+						"1.sql": "CREATE TABLE t1 (id INT);",
+					},
+				},
+			},
+		}
+		op = &dbv1alpha1.AtlasMigrationOperation{
+			ObjectMeta: metav1.ObjectMeta{Name: "resolve", Namespace: "default"},
+			Spec: dbv1alpha1.AtlasMigrationOperationSpec{
+				MigrationRef: corev1.LocalObjectReference{Name: mig.Name},
+				Type:         dbv1alpha1.MigrationOperationResolve,
+				Version:      "1",
+			},
+			Status: dbv1alpha1.AtlasMigrationOperationStatus{
+				Conditions: []metav1.Condition{
+					{Type: "Ready", Status: metav1.ConditionFalse},
+				},
+			},
+		}
+	)
+	mockExec := &mockAtlasExec{}
+	mockExec.status.res = &atlasexec.MigrateStatus{Current: "1"}
+	h, reconcile := newRunner(NewAtlasMigrationOperationReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(op)
+		cb.WithObjects(mig, op)
+	}, mockExec)
+	// Nothing to resolve.
+	reconcile(op, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+		require.EqualValues(t, ctrl.Result{}, result)
+	})
+	res := &dbv1alpha1.AtlasMigrationOperation{ObjectMeta: op.ObjectMeta}
+	h.get(t, res)
+	require.False(t, res.IsReady())
+	require.Equal(t, "NothingToResolve", res.Status.Conditions[0].Reason)
+	require.Nil(t, mockExec.set.res)
+	// The last migration failed.
+	mockExec.status.res = &atlasexec.MigrateStatus{Current: "0", Error: "near \"CREAT\": syntax error"}
+	reconcile(op, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+	})
+	h.get(t, res)
+	require.True(t, res.IsReady())
+	require.Equal(t, &MigrateSetParams{Env: "kubernetes", Version: "1"}, mockExec.set.res)
+}
+
+func TestMigrationOperation_Rehash(t *testing.T) {
+	var (
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "migrations", Namespace: "default"},
+			Data: map[string]string{
+				"1.sql":     "CREATE TABLE t1 (id INT);",
+				"atlas.sum": "invalid",
+			},
+		}
+		mig = &dbv1alpha1.AtlasMigration{
+			ObjectMeta: migrationObjmeta(),
+			Spec: dbv1alpha1.AtlasMigrationSpec{
+				TargetSpec: dbv1alpha1.TargetSpec{
+					URL: "sqlite://file?mode=memory",
+				},
+				Dir: dbv1alpha1.Dir{
+					ConfigMapRef: &corev1.LocalObjectReference{Name: cm.Name},
+				},
+			},
+		}
+		op = &dbv1alpha1.AtlasMigrationOperation{
+			ObjectMeta: metav1.ObjectMeta{Name: "rehash", Namespace: "default"},
+			Spec: dbv1alpha1.AtlasMigrationOperationSpec{
+				MigrationRef: corev1.LocalObjectReference{Name: mig.Name},
+				Type:         dbv1alpha1.MigrationOperationRehash,
+			},
+			Status: dbv1alpha1.AtlasMigrationOperationStatus{
+				Conditions: []metav1.Condition{
+					{Type: "Ready", Status: metav1.ConditionFalse},
+				},
+			},
+		}
+	)
+	h, reconcile := newRunner(NewAtlasMigrationOperationReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(op)
+		cb.WithObjects(cm, mig, op)
+	}, &mockAtlasExec{})
+	reconcile(op, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+		require.EqualValues(t, ctrl.Result{}, result)
+	})
+	res := &dbv1alpha1.AtlasMigrationOperation{ObjectMeta: op.ObjectMeta}
+	h.get(t, res)
+	require.True(t, res.IsReady())
+	dir := must(memDir(map[string]string{"1.sql": "CREATE TABLE t1 (id INT);"}))
+	sum := must(must(dir.Checksum()).MarshalText())
+	h.get(t, cm)
+	require.Equal(t, string(sum), cm.Data[migrate.HashFileName])
+
+	// ConfigMaps without data are rehashed too.
+	cm.Data = nil
+	require.NoError(t, h.client.Update(context.Background(), cm))
+	op2 := op.DeepCopy()
+	op2.ObjectMeta = metav1.ObjectMeta{Name: "rehash-empty", Namespace: "default"}
+	require.NoError(t, h.client.Create(context.Background(), op2))
+	reconcile(op2, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+		require.EqualValues(t, ctrl.Result{}, result)
+	})
+	h.get(t, op2)
+	require.True(t, op2.IsReady())
+	h.get(t, cm)
+	require.Contains(t, cm.Data, migrate.HashFileName)
+}
+
+func TestMigrationOperation_Down(t *testing.T) {
+	var (
+		mig = &dbv1alpha1.AtlasMigration{
+			ObjectMeta: migrationObjmeta(),
+			Spec: dbv1alpha1.AtlasMigrationSpec{
+				TargetSpec: dbv1alpha1.TargetSpec{
+					URL: "sqlite://file?mode=memory",
+				},
+				DevURL: "sqlite://dev?mode=memory",
+				Dir: dbv1alpha1.Dir{
+					Local: map[string]string{
+						"1.sql": "CREATE TABLE t1 (id INT);",
+					},
+				},
+			},
+		}
+		op = &dbv1alpha1.AtlasMigrationOperation{
+			ObjectMeta: metav1.ObjectMeta{Name: "down", Namespace: "default"},
+			Spec: dbv1alpha1.AtlasMigrationOperationSpec{
+				MigrationRef: corev1.LocalObjectReference{Name: mig.Name},
+				Type:         dbv1alpha1.MigrationOperationDown,
+			},
+			Status: dbv1alpha1.AtlasMigrationOperationStatus{
+				Conditions: []metav1.Condition{
+					{Type: "Ready", Status: metav1.ConditionFalse},
+				},
+			},
+		}
+	)
+	mockExec := &mockAtlasExec{}
+	mockExec.down.res = &atlasexec.MigrateDown{Current: "1", Target: "0", Reverted: []*atlasexec.RevertedFile{{}}}
+	h, reconcile := newRunner(NewAtlasMigrationOperationReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(op)
+		cb.WithObjects(mig, op)
+	}, mockExec)
+	assert := func(reason, msg string) {
+		t.Helper()
+		reconcile(op, func(result ctrl.Result, err error) {
+			require.NoError(t, err)
+			require.EqualValues(t, ctrl.Result{}, result)
+		})
+		res := &dbv1alpha1.AtlasMigrationOperation{ObjectMeta: op.ObjectMeta}
+		h.get(t, res)
+		require.False(t, res.IsReady())
+		require.Equal(t, reason, res.Status.Conditions[0].Reason)
+		require.Equal(t, msg, res.Status.Conditions[0].Message)
+	}
+	// The migrate-down flow of the migration is not allowed.
+	assert("ProtectedFlowError", "migrate down is not allowed, set `protectedFlows.migrateDown.allow` to true on AtlasMigration \"atlas-migration\" to allow it")
+	// The migration would re-apply the reverted files.
+	h.get(t, mig)
+	mig.Spec.ProtectedFlows = &dbv1alpha1.ProtectFlows{
+		MigrateDown: &dbv1alpha1.DeploymentFlow{Allow: true, AutoApprove: true},
+	}
+	require.NoError(t, h.client.Update(context.Background(), mig))
+	assert("MigrationNotPaused", "AtlasMigration \"atlas-migration\" must be paused to revert its migrations, or it re-applies them")
+	// Paused migrations can be reverted.
+	mig.Spec.Paused = true
+	require.NoError(t, h.client.Update(context.Background(), mig))
+	reconcile(op, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+	})
+	res := &dbv1alpha1.AtlasMigrationOperation{ObjectMeta: op.ObjectMeta}
+	h.get(t, res)
+	require.True(t, res.IsReady())
+	require.Equal(t, "0", res.Status.CurrentVersion)
+}
@@ -15,28 +15,38 @@
 package controller
 
 import (
+	"cmp"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/url"
 	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"ariga.io/atlas-go-sdk/atlasexec"
 	"github.com/ariga/atlas-operator/api/v1alpha1"
@@ -45,7 +55,8 @@ import (
 )
 
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=create;update;delete;get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=core,resources=configmaps;secrets,verbs=create;get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=create;get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps;secrets,verbs=create;update;get;list;watch
 //+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 //+kubebuilder:rbac:groups=core,resources=pods,verbs=create;delete;get;list;watch
 //+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasschemas,verbs=get;list;watch;create;update;patch;delete
@@ -60,8 +71,20 @@ type (
 		scheme           *runtime.Scheme
 		configMapWatcher *watch.ResourceWatcher
 		secretWatcher    *watch.ResourceWatcher
-		recorder         record.EventRecorder
-		devDB            *devDBReconciler
+		// deploymentWatcher triggers the contract phase of
+		// expand/contract rollouts gated on a Deployment.
+		deploymentWatcher *watch.ResourceWatcher
+		recorder          record.EventRecorder
+		devDB             *devDBReconciler
+		// tableStats returns the live statistics of the given
+		// tables, used by the impact analysis of planned changes.
+		tableStats tableStatsFn
+		// tenantSchemas returns the schemas of the target database,
+		// matched against the pattern of the tenants.
+		tenantSchemas tenantSchemasFn
+		// owners records the objects owned by each resource
+		// to detect resources that manage the same objects.
+		owners *ownership
 	}
 	// managedData contains information about the managed database and its desired state.
 	managedData struct {
@@ -70,12 +93,22 @@ type (
 		DevURL  string
 		Schemas []string
 		Exclude []string
+		Include []string
 		Policy  *dbv1alpha1.Policy
 		TxMode  dbv1alpha1.TransactionMode
+		Mode    dbv1alpha1.SchemaMode
 		Desired *url.URL
 		Cloud   *Cloud
+		TestRun string
+		// ExpandContract is true if the changes are rolled out in two phases.
+		// Expand is true while applying the first (expand) phase.
+		ExpandContract bool
+		Expand         bool
 
-		schema []byte
+		schema   []byte
+		tests    map[string]string
+		osc      *dbv1alpha1.OnlineSchemaChange
+		timeouts *dbv1alpha1.Timeouts
 	}
 )
 
@@ -84,13 +117,17 @@ const sqlLimitSize = 1024
 func NewAtlasSchemaReconciler(mgr Manager, atlas AtlasExecFn, prewarmDevDB bool) *AtlasSchemaReconciler {
 	r := mgr.GetEventRecorderFor("atlasschema-controller")
 	return &AtlasSchemaReconciler{
-		Client:           mgr.GetClient(),
-		scheme:           mgr.GetScheme(),
-		atlasClient:      atlas,
-		configMapWatcher: watch.New(),
-		secretWatcher:    watch.New(),
-		recorder:         r,
-		devDB:            newDevDB(mgr, r, prewarmDevDB),
+		Client:            mgr.GetClient(),
+		scheme:            mgr.GetScheme(),
+		atlasClient:       atlas,
+		configMapWatcher:  watch.New(),
+		secretWatcher:     watch.New(),
+		deploymentWatcher: watch.New(),
+		recorder:          r,
+		devDB:             newDevDB(mgr, r, prewarmDevDB),
+		tableStats:        queryTableStats,
+		tenantSchemas:     queryTenantSchemas,
+		owners:            newOwnership(),
 	}
 }
 
@@ -102,6 +139,9 @@ func (r *AtlasSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		res = &dbv1alpha1.AtlasSchema{}
 	)
 	if err = r.Get(ctx, req.NamespacedName, res); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.owners.release(req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 	defer func() {
@@ -124,13 +164,45 @@ func (r *AtlasSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		res.SetNotReady("Reconciling", "Reconciling")
 		return ctrl.Result{Requeue: true}, nil
 	}
+	// Released resources handed over their database to an AtlasMigration,
+	// and are not reconciled anymore. Nothing is deleted from the database.
+	if to := res.Annotations[dbv1alpha1.AnnotationReleasedTo]; to != "" {
+		r.owners.release(res.NamespacedName())
+		res.SetNotReady("Released", fmt.Sprintf("the database is managed by AtlasMigration %q", to))
+		return ctrl.Result{}, nil
+	}
+	// Resources with tenants apply the desired schema to the
+	// tenant schemas, by the resources created for the tenants.
+	if res.Spec.Tenants != nil {
+		return r.reconcileTenants(ctx, res)
+	}
 	data, err := r.extractData(ctx, res)
 	if err != nil {
 		res.SetNotReady("ReadSchema", err.Error())
 		r.recordErrEvent(res, err)
 		return result(err)
 	}
-	hash, err := data.hash()
+	if res.Spec.Mode == dbv1alpha1.SchemaModeInspect {
+		// Inspected resources do not change the database, and own no objects.
+		r.owners.release(res.NamespacedName())
+		return r.inspect(ctx, res, data)
+	}
+	// Refuse to manage objects that are owned by other resources.
+	if err = r.owners.sync(func() (map[types.NamespacedName]*claim, error) {
+		return r.claims(ctx)
+	}); err != nil {
+		res.SetNotReady("ReadOwnership", err.Error())
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	if err = r.owners.claim(res.NamespacedName(), data.claim()); err != nil {
+		reason, msg := "Conflict", err.Error()
+		res.SetNotReady(reason, msg)
+		r.recorder.Event(res, corev1.EventTypeWarning, reason, msg)
+		// Don't requeue, the resource is reconciled again once the conflict is resolved.
+		return ctrl.Result{}, nil
+	}
+	hash, hashes, err := data.hash()
 	if err != nil {
 		res.SetNotReady("CalculatingHash", err.Error())
 		r.recordErrEvent(res, err)
@@ -140,7 +212,11 @@ func (r *AtlasSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// any heavy jobs if the hash is different from the last applied.
 	// This is to ensure that other tools know we are still applying the changes.
 	if res.IsReady() && res.IsHashModified(hash) {
-		res.SetNotReady("Reconciling", "current schema does not match last applied")
+		msg := "current schema does not match last applied"
+		if changed := changedInputs(res.Status.ObservedHashes, hashes); len(changed) > 0 {
+			msg = fmt.Sprintf("%s, changed inputs: %s", msg, strings.Join(changed, ", "))
+		}
+		res.SetNotReady("Reconciling", msg)
 		return ctrl.Result{Requeue: true}, nil
 	}
 	// ====================================================
@@ -164,6 +240,9 @@ func (r *AtlasSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			return err
 		})
 	}
+	if len(data.tests) > 0 {
+		opts = append(opts, withTestFiles(data.tests))
+	}
 	// Create a working directory for the Atlas CLI
 	// The working directory contains the atlas.hcl config.
 	wd, err := atlasexec.NewWorkingDir(opts...)
@@ -179,6 +258,12 @@ func (r *AtlasSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		r.recordErrEvent(res, err)
 		return result(err)
 	}
+	// Report the schema changes with the same context as the migrations,
+	// and the resource that triggered them.
+	ctx = withDeployContext(ctx, &atlasexec.DeployRunContext{
+		TriggerType:    atlasexec.TriggerTypeKubernetes,
+		TriggerVersion: triggerVersion(ctx, res),
+	})
 	var whoami *atlasexec.WhoAmI
 	switch whoami, err = cli.WhoAmI(ctx); {
 	case errors.Is(err, atlasexec.ErrRequireLogin):
@@ -190,7 +275,121 @@ func (r *AtlasSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	default:
 		log.Info("the resource is connected to Atlas Cloud", "org", whoami.Org)
 	}
-	var report *atlasexec.SchemaApply
+	// Verify the desired schema satisfies the custom schema policies.
+	if err = r.checkPolicies(ctx, cli, data, res); err != nil {
+		reason, msg := "PolicyError", err.Error()
+		if v := (*policyViolationErr)(nil); errors.As(err, &v) {
+			reason = v.Reason()
+		}
+		res.SetNotReady(reason, msg)
+		r.recorder.Event(res, corev1.EventTypeWarning, reason, msg)
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	// Estimate the impact of the planned changes on the target database.
+	var (
+		impact       []dbv1alpha1.TableImpact
+		impactReview bool
+	)
+	if p := data.Policy; p != nil && p.Impact != nil {
+		impact, err = r.impact(ctx, cli, data)
+		res.Status.Impact = impact
+		switch e := (*impactErr)(nil); {
+		case errors.As(err, &e) && p.Impact.OnExceed == dbv1alpha1.ImpactActionReview && whoami != nil && data.repoURL() != nil:
+			log.Info("planned changes exceed the impact thresholds, requiring a review", "impact", impact)
+			impactReview = true
+		case errors.As(err, &e):
+			reason, msg := e.Reason(), err.Error()
+			res.SetNotReady(reason, msg)
+			r.recorder.Event(res, corev1.EventTypeWarning, reason, msg)
+			// Don't requeue, the changes are blocked until the desired state or the policy is changed.
+			return ctrl.Result{}, nil
+		case err != nil:
+			reason, msg := "ImpactAnalysis", err.Error()
+			res.SetNotReady(reason, msg)
+			r.recorder.Event(res, corev1.EventTypeWarning, reason, msg)
+			if !isSQLErr(err) {
+				err = transient(err)
+			}
+			r.recordErrEvent(res, err)
+			return result(err)
+		}
+	}
+	// Run the schema tests against the dev database before touching the target.
+	if len(data.tests) > 0 {
+		_, err = cli.SchemaTest(ctx, &atlasexec.SchemaTestParams{
+			Env: data.EnvName,
+			URL: data.Desired.String(),
+			Run: data.TestRun,
+		})
+		if err = testsResult(err); err != nil {
+			reason, msg := "TestingSchema", err.Error()
+			if e := (*testsErr)(nil); errors.As(err, &e) {
+				reason = e.Reason()
+			}
+			res.SetNotReady(reason, msg)
+			r.recorder.Event(res, corev1.EventTypeWarning, reason, msg)
+			r.recordErrEvent(res, err)
+			return result(err)
+		}
+	}
+	// Hold back the contracting changes of expand/contract rollouts.
+	var rollout *dbv1alpha1.RolloutStatus
+	if data.ExpandContract {
+		rollout, err = r.rollout(ctx, cli, wd, data, res)
+		switch e := (*contractPendingErr)(nil); {
+		case errors.As(err, &e):
+			res.Status.Rollout = rollout
+			res.SetNotReady(e.Reason(), err.Error())
+			return ctrl.Result{RequeueAfter: e.after}, nil
+		case err != nil:
+			reason, msg := "RollingOut", err.Error()
+			res.SetNotReady(reason, msg)
+			r.recorder.Event(res, corev1.EventTypeWarning, reason, msg)
+			if !isSQLErr(err) {
+				err = transient(err)
+			}
+			r.recordErrEvent(res, err)
+			return result(err)
+		}
+	}
+	contract := rollout != nil && rollout.Phase == dbv1alpha1.RolloutPhaseContracted
+	// Hold back the qualifying ALTER statements, they are run by the online schema change tool.
+	var (
+		oscJob    *batchv1.Job
+		oscStatus *dbv1alpha1.OnlineSchemaChangeStatus
+	)
+	if data.osc != nil {
+		oscJob, oscStatus, err = r.onlineSchemaChange(ctx, cli, wd, data, res)
+		pending, failed := (*oscPendingErr)(nil), (*oscFailedErr)(nil)
+		switch {
+		case errors.As(err, &pending):
+			res.Status.OnlineSchemaChange = oscStatus
+			res.SetNotReady(pending.Reason(), err.Error())
+			// Don't requeue, the resource is reconciled again once the job finishes.
+			return ctrl.Result{}, nil
+		case errors.As(err, &failed):
+			res.Status.OnlineSchemaChange = oscStatus
+			reason, msg := failed.Reason(), err.Error()
+			res.SetNotReady(reason, msg)
+			r.recorder.Event(res, corev1.EventTypeWarning, reason, msg)
+			return ctrl.Result{}, nil
+		case err != nil:
+			reason, msg := "OnlineSchemaChange", err.Error()
+			res.SetNotReady(reason, msg)
+			r.recorder.Event(res, corev1.EventTypeWarning, reason, msg)
+			if !isSQLErr(err) {
+				err = transient(err)
+			}
+			r.recordErrEvent(res, err)
+			return result(err)
+		}
+	}
+	var (
+		report *atlasexec.SchemaApply
+		diags  []dbv1alpha1.LintDiagnostic
+		acked  string
+	)
 	switch desiredURL := data.Desired.String(); {
 	// The resource is connected to Atlas Cloud.
 	case whoami != nil:
@@ -206,6 +405,9 @@ func (r *AtlasSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 				vars["lint_review"] = r
 			}
 		}
+		if impactReview {
+			vars["lint_review"] = dbv1alpha1.LintReviewAlways
+		}
 		params := &atlasexec.SchemaApplyParams{
 			Env:    data.EnvName,
 			Vars:   vars,
@@ -275,10 +477,13 @@ func (r *AtlasSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			switch {
 			case err != nil && strings.Contains(err.Error(), "no changes to be made"):
 				res.SetReady(dbv1alpha1.AtlasSchemaStatus{
-					LastApplied:  time.Now().Unix(),
-					ObservedHash: hash,
+					LastApplied:    time.Now().Unix(),
+					ObservedHash:   hash,
+					ObservedHashes: hashes,
 				}, nil)
 				r.recorder.Event(res, corev1.EventTypeNormal, "Applied", "Applied schema")
+				r.writeDocs(ctx, res, cli, data)
+				r.writeMigrations(ctx, res, cli, wd, data)
 				return ctrl.Result{}, nil
 			case err != nil:
 				reason, msg := "SchemaPlan", err.Error()
@@ -358,12 +563,27 @@ func (r *AtlasSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	// Verify the first run doesn't contain destructive changes.
 	case res.Status.LastApplied == 0:
-		err = r.lint(ctx, wd, data, atlasexec.Vars2{
+		diags, err = r.lint(ctx, wd, data, atlasexec.Vars2{
 			"lint_destructive": "true",
 		})
-		switch d := (*destructiveErr)(nil); {
-		case errors.As(err, &d):
-			reason, msg := d.FirstRun()
+		// Only destructive changes block the first run,
+		// the rest of the linting policy is not enforced.
+		if l := (*lintErr)(nil); errors.As(err, &l) {
+			err = nil
+		}
+		switch ds := destructive(diags); {
+		// The destructive changes were acknowledged by the user.
+		case len(ds) > 0 && res.Annotations[dbv1alpha1.AnnotationAckDestructive] == destructiveHash(ds):
+			acked = destructiveHash(ds)
+			for i := range diags {
+				diags[i].Error = diags[i].Error && analyzerOf(diags[i].Code) != 0
+			}
+			log.Info("applying acknowledged destructive changes", "hash", acked)
+			r.recorder.Eventf(res, corev1.EventTypeWarning, "DestructiveAcknowledged",
+				"Applying acknowledged destructive changes (%s):\n%s", acked, (&lintErr{diags: ds}).Error())
+		case len(ds) > 0:
+			res.Status.Diagnostics = diags
+			reason, msg := firstRunErr(ds)
 			res.SetNotReady(reason, msg)
 			r.recorder.Event(res, corev1.EventTypeWarning, reason, msg)
 			// Don't requeue destructive errors.
@@ -392,7 +612,12 @@ func (r *AtlasSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 				vars["lint_destructive"] = strconv.FormatBool(d.Error)
 			}
 		}
-		if err = r.lint(ctx, wd, data, vars); err != nil {
+		// The contract gate approves the destructive changes.
+		if contract {
+			vars["lint_destructive"] = "false"
+		}
+		if diags, err = r.lint(ctx, wd, data, vars); err != nil {
+			res.Status.Diagnostics = diags
 			reason, msg := "LintPolicyError", err.Error()
 			res.SetNotReady(reason, msg)
 			r.recorder.Event(res, corev1.EventTypeWarning, reason, msg)
@@ -418,6 +643,14 @@ func (r *AtlasSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			AutoApprove: true,
 		})
 	}
+	if isLockTimeout(err) {
+		// Nothing was applied by the failed statement, retry with a backoff.
+		res.Status.LockTimeouts++
+		reason, msg := "LockTimeout", fmt.Sprintf("%s\nAttempt %d failed on a lock timeout, retrying", err, res.Status.LockTimeouts)
+		res.SetNotReady(reason, msg)
+		r.recorder.Event(res, corev1.EventTypeWarning, reason, msg)
+		return result(lockTimeoutRetry(err, res.Status.LockTimeouts))
+	}
 	if err != nil {
 		res.SetNotReady("ApplyingSchema", err.Error())
 		r.recorder.Event(res, corev1.EventTypeWarning, "ApplyingSchema", err.Error())
@@ -432,8 +665,18 @@ func (r *AtlasSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	report.Changes.Applied = truncateSQL(report.Changes.Applied, sqlLimitSize)
 	report.Changes.Pending = truncateSQL(report.Changes.Pending, sqlLimitSize)
 	s := dbv1alpha1.AtlasSchemaStatus{
-		LastApplied:  time.Now().Unix(),
-		ObservedHash: hash,
+		LastApplied:    time.Now().Unix(),
+		ObservedHash:   hash,
+		ObservedHashes: hashes,
+		Diagnostics:    diags,
+		Impact:         impact,
+		// Keep the acknowledged changes for auditing.
+		AcknowledgedDestructive: cmp.Or(acked, res.Status.AcknowledgedDestructive),
+		Rollout:                 cmp.Or(rollout, res.Status.Rollout),
+		OnlineSchemaChange:      cmp.Or(oscStatus, res.Status.OnlineSchemaChange),
+	}
+	if rollout != nil {
+		rolledOut(rollout, report)
 	}
 	// Set the plan URL if it exists.
 	if p := report.Plan; p != nil {
@@ -441,17 +684,49 @@ func (r *AtlasSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		s.PlanURL = p.File.URL
 	}
 	res.SetReady(s, report)
+	if oscJob != nil {
+		// The resource is not ready until the online schema changes complete.
+		if err = r.runOnlineSchemaChange(ctx, res, data, oscJob); err != nil {
+			reason, msg := "OnlineSchemaChange", err.Error()
+			res.SetNotReady(reason, msg)
+			r.recorder.Event(res, corev1.EventTypeWarning, reason, msg)
+			err = transient(err)
+			r.recordErrEvent(res, err)
+			return result(err)
+		}
+		res.SetNotReady("OnlineSchemaChange", (&oscPendingErr{job: oscJob.Name, table: oscStatus.Table}).Error())
+		r.recorder.Eventf(res, corev1.EventTypeNormal, "OnlineSchemaChange", "Started the online schema change of table %s in job %q", oscStatus.Table, oscJob.Name)
+		return ctrl.Result{}, nil
+	}
+	if rollout != nil && rollout.Phase == dbv1alpha1.RolloutPhaseExpanded {
+		// The resource is not ready until the contracting changes are applied.
+		res.SetNotReady("ContractPending", "The expand phase has been applied, the contracting changes are held back")
+		r.recorder.Eventf(res, corev1.EventTypeNormal, "Expanded", "Applied the expand phase, holding back %d contracting changes", len(rollout.Contract))
+		r.writeDocs(ctx, res, cli, data)
+		return ctrl.Result{Requeue: true}, nil
+	}
 	r.recorder.Event(res, corev1.EventTypeNormal, "Applied", "Applied schema")
+	r.writeDocs(ctx, res, cli, data)
+	r.writeMigrations(ctx, res, cli, wd, data)
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *AtlasSchemaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.owners.events = make(chan event.GenericEvent)
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&dbv1alpha1.AtlasSchema{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		For(&dbv1alpha1.AtlasSchema{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			// Destructive changes are acknowledged by annotating the resource.
+			predicate.AnnotationChangedPredicate{},
+		))).
 		Owns(&dbv1alpha1.AtlasSchema{}).
 		Watches(&corev1.ConfigMap{}, r.configMapWatcher).
 		Watches(&corev1.Secret{}, r.secretWatcher).
+		Owns(&batchv1.Job{}).
+		Watches(&appsv1.Deployment{}, r.deploymentWatcher).
+		Watches(&dbv1alpha1.AtlasSchemaPolicy{}, handler.EnqueueRequestsFromMapFunc(r.mapPolicy)).
+		WatchesRawSource(source.Channel(r.owners.events, &handler.EnqueueRequestForObject{})).
 		Complete(r)
 }
 
@@ -462,6 +737,18 @@ func (r *AtlasSchemaReconciler) watchRefs(res *dbv1alpha1.AtlasSchema) {
 			res.NamespacedName(),
 		)
 	}
+	if ro := res.Spec.Rollout; ro != nil && ro.Contract != nil && ro.Contract.DeploymentRef != nil {
+		r.deploymentWatcher.Watch(
+			types.NamespacedName{Name: ro.Contract.DeploymentRef.Name, Namespace: res.Namespace},
+			res.NamespacedName(),
+		)
+	}
+	if t := res.Spec.Tests; t != nil {
+		r.configMapWatcher.Watch(
+			types.NamespacedName{Name: t.ConfigMapRef.Name, Namespace: res.Namespace},
+			res.NamespacedName(),
+		)
+	}
 	if s := res.Spec.Cloud.TokenFrom.SecretKeyRef; s != nil {
 		r.secretWatcher.Watch(
 			types.NamespacedName{Name: s.Name, Namespace: res.Namespace},
@@ -498,10 +785,15 @@ func (r *AtlasSchemaReconciler) extractData(ctx context.Context, res *dbv1alpha1
 			DevURL:  s.DevURL,
 			Schemas: s.Schemas,
 			Exclude: s.Exclude,
+			Include: s.Include,
 			Policy:  s.Policy,
 			TxMode:  s.TxMode,
+			Mode:    s.Mode,
 		}
 	)
+	if env := s.EnvName; env != "" {
+		data.EnvName = env
+	}
 	if s := c.TokenFrom.SecretKeyRef; s != nil {
 		token, err := getSecretValue(ctx, r, res.Namespace, s)
 		if err != nil {
@@ -509,17 +801,37 @@ func (r *AtlasSchemaReconciler) extractData(ctx context.Context, res *dbv1alpha1
 		}
 		data.Cloud = &Cloud{Token: token}
 	}
-	if r := c.Repo; r != "" {
+	if c.Repo != "" || c.URL != "" || c.Project != "" {
 		if data.Cloud == nil {
 			// The ATLAS_TOKEN token can be provide via the environment variable.
 			data.Cloud = &Cloud{}
 		}
-		data.Cloud.Repo = r
+		data.Cloud.Repo = c.Repo
+		data.Cloud.URL = c.URL
+		data.Cloud.Project = c.Project
 	}
 	data.URL, err = s.DatabaseURL(ctx, r, res.Namespace)
 	if err != nil {
 		return nil, transient(err)
 	}
+	if msg := ignoredTimeouts(data.URL, s.Timeouts); msg != "" {
+		r.recorder.Event(res, corev1.EventTypeWarning, "TimeoutsIgnored", msg)
+	}
+	data.timeouts = s.Timeouts
+	if p := s.Policy; p != nil && p.Impact != nil {
+		switch data.URL.Scheme {
+		case "mysql", "maria", "mariadb", "postgres", "postgresql":
+		default:
+			return nil, &ProtectedFlowError{
+				reason: "ImpactAnalysis",
+				msg:    fmt.Sprintf("impact analysis is not supported for %q databases", data.URL.Scheme),
+			}
+		}
+	}
+	// The desired state is not used in inspect mode.
+	if data.Mode == dbv1alpha1.SchemaModeInspect {
+		return data, nil
+	}
 	data.Desired, data.schema, err = s.Schema.DesiredState(ctx, r, res.Namespace)
 	if err != nil {
 		return nil, transient(err)
@@ -532,9 +844,62 @@ func (r *AtlasSchemaReconciler) extractData(ctx context.Context, res *dbv1alpha1
 			return nil, err
 		}
 	}
+	if t := s.Tests; t != nil {
+		if data.tests, err = testFiles(ctx, r, res.Namespace, t); err != nil {
+			return nil, err
+		}
+		data.TestRun = t.Run
+	}
+	if ro := s.Rollout; ro != nil && ro.Strategy == dbv1alpha1.RolloutStrategyExpandContract {
+		if g := ro.Contract; g == nil || !g.Annotation && g.DeploymentRef == nil && g.Delay == nil {
+			return nil, errors.New("expand-contract rollouts require a contract gate: annotation, deploymentRef or delay")
+		}
+		data.ExpandContract = true
+	}
+	if o := s.OnlineSchemaChange; o != nil {
+		switch dbv1alpha1.DriverBySchema(data.URL.Scheme) {
+		case dbv1alpha1.DriverMySQL, dbv1alpha1.DriverMariaDB:
+		default:
+			return nil, &ProtectedFlowError{
+				reason: "OnlineSchemaChange",
+				msg:    fmt.Sprintf("online schema changes are not supported for %q databases", data.URL.Scheme),
+			}
+		}
+		data.osc = o
+	}
 	return data, nil
 }
 
+// claims returns the objects owned by the existing resources. Resources
+// that do not change the database, or whose URL cannot be resolved, own
+// no objects.
+func (r *AtlasSchemaReconciler) claims(ctx context.Context) (map[types.NamespacedName]*claim, error) {
+	var list dbv1alpha1.AtlasSchemaList
+	if err := r.List(ctx, &list); err != nil {
+		return nil, transient(err)
+	}
+	claims := make(map[types.NamespacedName]*claim, len(list.Items))
+	for i := range list.Items {
+		res := &list.Items[i]
+		s := res.Spec
+		if !res.DeletionTimestamp.IsZero() || res.Annotations[dbv1alpha1.AnnotationReleasedTo] != "" ||
+			s.Tenants != nil || s.Mode == dbv1alpha1.SchemaModeInspect {
+			continue
+		}
+		u, err := s.DatabaseURL(ctx, r, res.Namespace)
+		if err != nil {
+			continue
+		}
+		claims[res.NamespacedName()] = (&managedData{
+			URL:     u,
+			Schemas: s.Schemas,
+			Include: s.Include,
+			Exclude: s.Exclude,
+		}).claim()
+	}
+	return claims, nil
+}
+
 func (r *AtlasSchemaReconciler) recordErrEvent(res *dbv1alpha1.AtlasSchema, err error) {
 	reason := "Error"
 	if isTransient(err) {
@@ -543,24 +908,145 @@ func (r *AtlasSchemaReconciler) recordErrEvent(res *dbv1alpha1.AtlasSchema, err
 	r.recorder.Event(res, corev1.EventTypeWarning, reason, strings.TrimSpace(err.Error()))
 }
 
-// ShouldLint returns true if the linting policy is set to error.
+// ShouldLint returns true if the destructive analyzer is set
+// to error, or any other analyzer of the linting policy is set.
 func (d *managedData) shouldLint() bool {
 	p := d.Policy
-	if p == nil || p.Lint == nil || p.Lint.Destructive == nil {
+	if p == nil || p.Lint == nil {
 		return false
 	}
-	return p.Lint.Destructive.Error
+	if l := p.Lint; l.Destructive != nil && l.Destructive.Error {
+		return true
+	}
+	for _, a := range lintAnalyzers[1:] {
+		if a.check(p.Lint) != nil {
+			return true
+		}
+	}
+	return false
 }
 
-// hash returns the sha256 hash of the desired.
-func (d *managedData) hash() (string, error) {
+// hash returns the sha256 hash of all the inputs of the reconciliation,
+// and its breakdown by input. The hash of each input is truncated to
+// 16 characters, and the returned hash is computed from all of them.
+// Passwords and tokens are left out of the breakdown, which is published
+// in the status, and are hashed only into the returned hash.
+func (d *managedData) hash() (string, map[string]string, error) {
+	desired := d.schema
+	if len(desired) == 0 {
+		desired = []byte(d.Desired.String())
+	}
+	policy, err := json.Marshal(d.Policy)
+	if err != nil {
+		return "", nil, err
+	}
+	var (
+		secrets []string
+		cloud   []byte
+		devURL  = d.DevURL
+	)
+	if p, ok := d.URL.User.Password(); ok {
+		secrets = append(secrets, p)
+	}
+	if u, err := url.Parse(d.DevURL); err == nil {
+		if p, ok := u.User.Password(); ok {
+			secrets = append(secrets, p)
+			devURL = u.Redacted()
+		}
+	}
+	if c := d.Cloud; c != nil {
+		secrets = append(secrets, c.Token)
+		cloud = []byte(strings.Join([]string{c.Repo, c.URL, c.Project}, "\x00"))
+	}
+	inputs := map[string][]byte{
+		"schema":  desired,
+		"url":     []byte(d.URL.Redacted()),
+		"devURL":  []byte(devURL),
+		"schemas": []byte(strings.Join(slices.Sorted(slices.Values(d.Schemas)), ",")),
+		"exclude": []byte(strings.Join(slices.Sorted(slices.Values(d.Exclude)), ",")),
+		"policy":  policy,
+		"txMode":  []byte(d.TxMode),
+		"envName": []byte(d.EnvName),
+		"cloud":   cloud,
+	}
+	// Include patterns and tests are hashed only when defined,
+	// to keep the hash of resources without them stable.
+	if len(d.Include) > 0 {
+		inputs["include"] = []byte(strings.Join(slices.Sorted(slices.Values(d.Include)), ","))
+	}
+	if d.ExpandContract {
+		inputs["rollout"] = []byte(dbv1alpha1.RolloutStrategyExpandContract)
+	}
+	if d.timeouts != nil {
+		timeouts, err := json.Marshal(d.timeouts)
+		if err != nil {
+			return "", nil, err
+		}
+		inputs["timeouts"] = timeouts
+	}
+	if d.osc != nil {
+		osc, err := json.Marshal(d.osc)
+		if err != nil {
+			return "", nil, err
+		}
+		inputs["onlineSchemaChange"] = osc
+	}
+	if len(d.tests) > 0 {
+		var b strings.Builder
+		b.WriteString(d.TestRun + "\x00")
+		for _, k := range slices.Sorted(maps.Keys(d.tests)) {
+			b.WriteString(k + "\x00" + d.tests[k] + "\x00")
+		}
+		inputs["tests"] = []byte(b.String())
+	}
 	h := sha256.New()
-	if len(d.schema) > 0 {
-		h.Write([]byte(d.schema))
-	} else {
-		h.Write([]byte(d.Desired.String()))
+	hashes := make(map[string]string, len(inputs))
+	for _, k := range slices.Sorted(maps.Keys(inputs)) {
+		s := sha256.Sum256(inputs[k])
+		hashes[k] = hex.EncodeToString(s[:])[:16]
+		h.Write([]byte(k + "=" + hashes[k] + "\n"))
+	}
+	if len(secrets) > 0 {
+		h.Write([]byte(strings.Join(secrets, "\x00")))
+	}
+	return hex.EncodeToString(h.Sum(nil)), hashes, nil
+}
+
+// changedInputs returns the names of the inputs whose hash differs
+// between the observed and current breakdowns, in sorted order.
+func changedInputs(observed, current map[string]string) []string {
+	if len(observed) == 0 {
+		return nil
 	}
-	return hex.EncodeToString(h.Sum(nil)), nil
+	var changed []string
+	for _, k := range slices.Sorted(maps.Keys(current)) {
+		if observed[k] != current[k] {
+			changed = append(changed, k)
+		}
+	}
+	return changed
+}
+
+// claim returns the objects of the target database owned by the resource.
+func (d *managedData) claim() *claim {
+	return &claim{
+		db:      databaseKey(d.URL),
+		schemas: d.Schemas,
+		include: d.Include,
+		exclude: d.Exclude,
+	}
+}
+
+// TargetURL returns the URL of the target database with its session
+// settings, used by the atlas.hcl template.
+func (d *managedData) TargetURL() *url.URL {
+	u, _ := sessionURL(d.URL, d.timeouts)
+	return u
+}
+
+// TestSrc returns the paths of the test files, used by the atlas.hcl template.
+func (d *managedData) TestSrc() []string {
+	return testSrc(d.tests)
 }
 
 func (d *managedData) repoURL() *url.URL {
@@ -592,11 +1078,14 @@ func (d *managedData) render(w io.Writer) error {
 	if d.URL == nil {
 		return errors.New("database url is not set")
 	}
-	if d.DevURL == "" {
-		return errors.New("dev url is not set")
-	}
-	if d.Desired == nil {
-		return errors.New("the desired state is not set")
+	// Inspecting the target database requires neither a dev database nor a desired state.
+	if d.Mode != dbv1alpha1.SchemaModeInspect {
+		if d.DevURL == "" {
+			return errors.New("dev url is not set")
+		}
+		if d.Desired == nil {
+			return errors.New("the desired state is not set")
+		}
 	}
 	return tmpl.ExecuteTemplate(w, "atlas_schema.tmpl", d)
 }
@@ -0,0 +1,204 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+//+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasschemacomparisons,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasschemacomparisons/finalizers,verbs=update
+//+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasschemacomparisons/status,verbs=get;update;patch
+
+type (
+	// AtlasSchemaComparisonReconciler reconciles a AtlasSchemaComparison object
+	AtlasSchemaComparisonReconciler struct {
+		client.Client
+		scheme      *runtime.Scheme
+		atlasClient AtlasExecFn
+		recorder    record.EventRecorder
+		devDB       *devDBReconciler
+	}
+)
+
+func NewAtlasSchemaComparisonReconciler(mgr Manager, atlas AtlasExecFn, prewarmDevDB bool) *AtlasSchemaComparisonReconciler {
+	r := mgr.GetEventRecorderFor("atlasschemacomparison-controller")
+	return &AtlasSchemaComparisonReconciler{
+		Client:      mgr.GetClient(),
+		scheme:      mgr.GetScheme(),
+		atlasClient: atlas,
+		recorder:    r,
+		devDB:       newDevDB(mgr, r, prewarmDevDB),
+	}
+}
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *AtlasSchemaComparisonReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	var (
+		log = ctrl.LoggerFrom(ctx)
+		res = &dbv1alpha1.AtlasSchemaComparison{}
+	)
+	if err = r.Get(ctx, req.NamespacedName, res); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	defer func() {
+		if err := r.Status().Update(ctx, res); err != nil {
+			log.Error(err, "failed to update resource status")
+		}
+	}()
+	// When the resource is first created, create the "Ready" condition.
+	if len(res.Status.Conditions) == 0 {
+		res.SetNotReady("Reconciling", "Reconciling")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	data, err := r.extractData(ctx, res)
+	if err != nil {
+		res.SetNotReady("ReadingTargets", err.Error())
+		r.recordErrEvent(res, err)
+		return r.result(res, err)
+	}
+	if data.DevURL == "" {
+		// The user has not specified an URL for dev-db,
+		// spin up a dev-db and get the connection string.
+		data.DevURL, err = r.devDB.devURL(ctx, res, *data.URL)
+		if err != nil {
+			res.SetNotReady("GettingDevDB", err.Error())
+			return result(err)
+		}
+	}
+	diff, err := r.compare(ctx, data)
+	if err != nil {
+		res.SetNotReady("Comparing", err.Error())
+		r.recordErrEvent(res, err)
+		return r.result(res, err)
+	}
+	// The dev database is not needed until the next comparison.
+	r.devDB.cleanUp(ctx, res)
+	s := dbv1alpha1.AtlasSchemaComparisonStatus{
+		Diff:         strings.Join(truncateSQL(diff, sqlLimitSize), "\n"),
+		Changes:      len(diff),
+		LastCompared: time.Now().Unix(),
+	}
+	switch synced := res.IsReady(); {
+	case len(diff) == 0:
+		if !synced {
+			r.recorder.Event(res, corev1.EventTypeNormal, "InSync", "The schemas are in sync")
+		}
+		res.SetInSync(s)
+	default:
+		if synced {
+			r.recorder.Eventf(res, corev1.EventTypeWarning, "Diverged", "The schemas differ by %d changes", len(diff))
+		}
+		res.SetDiverged(s)
+	}
+	return ctrl.Result{RequeueAfter: res.Interval()}, nil
+}
+
+// extractData resolves the compared databases. The source database is the
+// target of the env, and the target database is its desired state.
+func (r *AtlasSchemaComparisonReconciler) extractData(ctx context.Context, res *dbv1alpha1.AtlasSchemaComparison) (*managedData, error) {
+	s := res.Spec
+	src, err := s.Source.DatabaseURL(ctx, r, res.Namespace)
+	if err != nil {
+		return nil, transient(fmt.Errorf("source: %w", err))
+	}
+	to, err := s.Target.DatabaseURL(ctx, r, res.Namespace)
+	if err != nil {
+		return nil, transient(fmt.Errorf("target: %w", err))
+	}
+	data := &managedData{
+		EnvName: defaultEnvName,
+		URL:     src,
+		DevURL:  s.DevURL,
+		Schemas: s.Schemas,
+		Exclude: s.Exclude,
+		Desired: to,
+	}
+	if s := s.DevURLFrom.SecretKeyRef; s != nil {
+		if data.DevURL, err = getSecretValue(ctx, r, res.Namespace, s); err != nil {
+			return nil, transient(err)
+		}
+	}
+	return data, nil
+}
+
+// compare returns the statements that bring the source schema to the target schema.
+// The diff is computed by a dry-run of the schema apply command on the source database.
+func (r *AtlasSchemaComparisonReconciler) compare(ctx context.Context, data *managedData) ([]string, error) {
+	wd, err := atlasexec.NewWorkingDir(atlasexec.WithAtlasHCL(data.render))
+	if err != nil {
+		return nil, err
+	}
+	defer wd.Close()
+	cli, err := r.atlasClient(wd.Path(), nil)
+	if err != nil {
+		return nil, err
+	}
+	report, err := cli.SchemaApply(ctx, &atlasexec.SchemaApplyParams{
+		Env:    data.EnvName,
+		To:     data.Desired.String(),
+		DryRun: true,
+	})
+	if err != nil {
+		if !isSQLErr(err) {
+			err = transient(err)
+		}
+		return nil, err
+	}
+	return report.Changes.Pending, nil
+}
+
+// result returns the result of a failed comparison. Transient errors are retried,
+// and the other errors are retried on the next comparison.
+func (r *AtlasSchemaComparisonReconciler) result(res *dbv1alpha1.AtlasSchemaComparison, err error) (ctrl.Result, error) {
+	if isTransient(err) {
+		return result(err)
+	}
+	return ctrl.Result{RequeueAfter: res.Interval()}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AtlasSchemaComparisonReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dbv1alpha1.AtlasSchemaComparison{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
+
+func (r *AtlasSchemaComparisonReconciler) recordErrEvent(res *dbv1alpha1.AtlasSchemaComparison, err error) {
+	reason := "Error"
+	switch e := (interface{ Reason() string })(nil); {
+	case errors.As(err, &e):
+		reason = e.Reason()
+	case isTransient(err):
+		reason = "TransientErr"
+	}
+	r.recorder.Event(res, corev1.EventTypeWarning, reason, strings.TrimSpace(err.Error()))
+}
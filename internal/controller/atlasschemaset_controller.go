@@ -0,0 +1,152 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+//+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasschemasets,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasschemasets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasschemasets/status,verbs=get;update;patch
+
+type (
+	// AtlasSchemaSetReconciler reconciles a AtlasSchemaSet object
+	AtlasSchemaSetReconciler struct {
+		client.Client
+		scheme   *runtime.Scheme
+		recorder record.EventRecorder
+		// catalogRows runs the query of a query generator.
+		catalogRows catalogRowsFn
+	}
+)
+
+func NewAtlasSchemaSetReconciler(mgr Manager, _ AtlasExecFn, _ bool) *AtlasSchemaSetReconciler {
+	return &AtlasSchemaSetReconciler{
+		Client:      mgr.GetClient(),
+		scheme:      mgr.GetScheme(),
+		recorder:    mgr.GetEventRecorderFor("atlasschemaset-controller"),
+		catalogRows: queryCatalogRows,
+	}
+}
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *AtlasSchemaSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	var (
+		log = ctrl.LoggerFrom(ctx)
+		res = &dbv1alpha1.AtlasSchemaSet{}
+	)
+	if err = r.Get(ctx, req.NamespacedName, res); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	defer func() {
+		if err := r.Status().Update(ctx, res); err != nil {
+			log.Error(err, "failed to update resource status")
+		}
+	}()
+	// When the resource is first created, create the "Ready" condition.
+	if len(res.Status.Conditions) == 0 {
+		res.SetNotReady("Reconciling", "Reconciling")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	targets, err := generateTargets(ctx, r, res.Namespace, res.Spec.Generator, r.catalogRows)
+	if err != nil {
+		res.SetNotReady("GeneratingTargets", err.Error())
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	items := make([]fanoutItem, 0, len(targets))
+	for _, t := range targets {
+		name, err := childName(res.Name, t)
+		if err != nil {
+			res.SetNotReady("ReconcilingTargets", err.Error())
+			r.recordErrEvent(res, err)
+			return result(err)
+		}
+		sc := &dbv1alpha1.AtlasSchema{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: res.Namespace},
+		}
+		items = append(items, fanoutItem{
+			name:  t.Name,
+			child: sc,
+			mutate: func() {
+				childMeta(res.Name, res.Spec.Template.Metadata, sc)
+				sc.Spec = *res.Spec.Template.Spec.DeepCopy()
+				sc.Spec.TargetSpec = *t.TargetSpec.DeepCopy()
+			},
+			status: func() dbv1alpha1.SetTargetStatus {
+				return targetStatus(t.Name, sc, sc.Status.Conditions)
+			},
+		})
+	}
+	f := &fanout{Client: r, scheme: r.scheme, recorder: r.recorder, kind: "AtlasSchema", noun: "target"}
+	statuses, err := f.reconcile(ctx, res, items, &dbv1alpha1.AtlasSchemaList{}, setChildren(res)...)
+	if err != nil {
+		res.SetNotReady("ReconcilingTargets", err.Error())
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	res.SetTargets(statuses)
+	return setResult(res.Spec.Generator), nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AtlasSchemaSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dbv1alpha1.AtlasSchemaSet{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&dbv1alpha1.AtlasSchema{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.mapSecret)).
+		Complete(r)
+}
+
+// mapSecret returns the requests of the AtlasSchemaSet resources selecting the secret.
+func (r *AtlasSchemaSetReconciler) mapSecret(ctx context.Context, o client.Object) []reconcile.Request {
+	list := &dbv1alpha1.AtlasSchemaSetList{}
+	if err := r.List(ctx, list, client.InNamespace(o.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list AtlasSchemaSet resources")
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i := range list.Items {
+		if matchSecret(list.Items[i].Spec.Generator, o) {
+			reqs = append(reqs, reconcile.Request{NamespacedName: list.Items[i].NamespacedName()})
+		}
+	}
+	return reqs
+}
+
+func (r *AtlasSchemaSetReconciler) recordErrEvent(res *dbv1alpha1.AtlasSchemaSet, err error) {
+	reason := "Error"
+	if isTransient(err) {
+		reason = "TransientErr"
+	}
+	r.recorder.Event(res, corev1.EventTypeWarning, reason, strings.TrimSpace(err.Error()))
+}
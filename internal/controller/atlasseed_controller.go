@@ -0,0 +1,492 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ariga.io/atlas/sql/migrate"
+	"ariga.io/atlas/sql/sqlclient"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+	"github.com/ariga/atlas-operator/internal/controller/watch"
+)
+
+//+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasseeds,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasseeds/finalizers,verbs=update
+//+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasseeds/status,verbs=get;update;patch
+
+type (
+	// AtlasSeedReconciler reconciles a AtlasSeed object
+	AtlasSeedReconciler struct {
+		client.Client
+		scheme           *runtime.Scheme
+		recorder         record.EventRecorder
+		configMapWatcher *watch.ResourceWatcher
+		// execSeed applies the seed files to the given database.
+		execSeed seedExecFn
+	}
+	// seedExecFn applies the seed files to the database, in one transaction.
+	seedExecFn func(context.Context, *url.URL, []seedFile) (*seedResult, error)
+	// seedFile is a key of the seed ConfigMap. It holds either
+	// SQL statements, or rows that are upserted into a table.
+	seedFile struct {
+		Name  string
+		Stmts []string
+		Rows  *seedRows
+	}
+	// seedRows are the rows of a structured seed file.
+	seedRows struct {
+		Table string           `json:"table"`
+		Key   []string         `json:"key"`
+		Rows  []map[string]any `json:"rows"`
+	}
+	// seedResult is the outcome of a seed execution.
+	seedResult struct {
+		Statements, Rows int
+	}
+	// seedStmt is an upsert statement and its arguments.
+	seedStmt struct {
+		query string
+		args  []any
+	}
+	// seedTarget is the state of the seeded database.
+	seedTarget struct {
+		kind, name string
+		url        *url.URL
+		// hash identifies the applied schema of the target. The data
+		// is re-applied every time a new schema or version is applied.
+		hash string
+		// waiting is set if the target is not ready to be seeded.
+		waiting string
+	}
+)
+
+func NewAtlasSeedReconciler(mgr Manager, _ AtlasExecFn, _ bool) *AtlasSeedReconciler {
+	return &AtlasSeedReconciler{
+		Client:           mgr.GetClient(),
+		scheme:           mgr.GetScheme(),
+		recorder:         mgr.GetEventRecorderFor("atlasseed-controller"),
+		configMapWatcher: watch.New(),
+		execSeed:         execSeed,
+	}
+}
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *AtlasSeedReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	var (
+		log = ctrl.LoggerFrom(ctx)
+		res = &dbv1alpha1.AtlasSeed{}
+	)
+	if err = r.Get(ctx, req.NamespacedName, res); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	defer func() {
+		if err := r.Status().Update(ctx, res); err != nil {
+			log.Error(err, "failed to update resource status")
+		}
+	}()
+	// When the resource is first created, create the "Ready" condition.
+	if len(res.Status.Conditions) == 0 {
+		res.SetNotReady("Reconciling", "Reconciling")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	r.configMapWatcher.Watch(
+		types.NamespacedName{Name: res.Spec.ConfigMapRef.Name, Namespace: res.Namespace},
+		res.NamespacedName(),
+	)
+	target, err := r.target(ctx, res)
+	if err != nil {
+		res.SetNotReady("ReadingTarget", err.Error())
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	// The data is applied once the target is ready. The seed is
+	// reconciled again when the status of the target changes.
+	if target.waiting != "" {
+		res.SetNotReady("WaitingForTarget", target.waiting)
+		return ctrl.Result{}, nil
+	}
+	files, data, err := r.readFiles(ctx, res)
+	if err != nil {
+		res.SetNotReady("ReadingData", err.Error())
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	hash := hashSeed(target, data)
+	// Skip if the data and the target are unchanged since the last apply.
+	if res.IsReady() && !res.IsHashModified(hash) {
+		return ctrl.Result{}, nil
+	}
+	report, err := r.execSeed(ctx, target.url, files)
+	if err != nil {
+		res.SetNotReady("Seeding", err.Error())
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	res.SetReady(dbv1alpha1.AtlasSeedStatus{
+		ObservedHash: hash,
+		Statements:   report.Statements,
+		Rows:         report.Rows,
+		LastApplied:  time.Now().Unix(),
+	})
+	r.recorder.Eventf(res, corev1.EventTypeNormal, "Applied", "Applied %d statements and upserted %d rows to the database of %s %q",
+		report.Statements, report.Rows, target.kind, target.name)
+	return ctrl.Result{}, nil
+}
+
+// target returns the state of the database referenced by the seed.
+func (r *AtlasSeedReconciler) target(ctx context.Context, res *dbv1alpha1.AtlasSeed) (*seedTarget, error) {
+	switch s := res.Spec; {
+	case s.SchemaRef != nil:
+		sc := &dbv1alpha1.AtlasSchema{}
+		if err := r.Get(ctx, types.NamespacedName{Name: s.SchemaRef.Name, Namespace: res.Namespace}, sc); err != nil {
+			return nil, transient(fmt.Errorf("reading AtlasSchema %q: %w", s.SchemaRef.Name, err))
+		}
+		t := &seedTarget{kind: "AtlasSchema", name: sc.Name, hash: sc.Status.ObservedHash}
+		if !sc.IsReady() {
+			t.waiting = fmt.Sprintf("AtlasSchema %q is not ready", sc.Name)
+			return t, nil
+		}
+		u, err := sc.Spec.DatabaseURL(ctx, r, res.Namespace)
+		if err != nil {
+			return nil, transient(err)
+		}
+		t.url = u
+		return t, nil
+	case s.MigrationRef != nil:
+		mig := &dbv1alpha1.AtlasMigration{}
+		if err := r.Get(ctx, types.NamespacedName{Name: s.MigrationRef.Name, Namespace: res.Namespace}, mig); err != nil {
+			return nil, transient(fmt.Errorf("reading AtlasMigration %q: %w", s.MigrationRef.Name, err))
+		}
+		v := mig.Status.LastAppliedVersion
+		t := &seedTarget{kind: "AtlasMigration", name: mig.Name, hash: mig.Status.ObservedHash + "@" + v}
+		switch {
+		case !mig.IsReady():
+			t.waiting = fmt.Sprintf("AtlasMigration %q is not ready", mig.Name)
+			return t, nil
+		case s.MinVersion != "" && compareVersions(v, s.MinVersion) < 0:
+			t.waiting = fmt.Sprintf("AtlasMigration %q is at version %q, waiting for version %q", mig.Name, v, s.MinVersion)
+			return t, nil
+		}
+		u, err := mig.Spec.DatabaseURL(ctx, r, res.Namespace)
+		if err != nil {
+			return nil, transient(err)
+		}
+		t.url = u
+		return t, nil
+	default:
+		return nil, errors.New("one of schemaRef and migrationRef must be set")
+	}
+}
+
+// readFiles reads the seed files from the referenced ConfigMap, in lexical order.
+// Keys with unknown extensions are ignored.
+func (r *AtlasSeedReconciler) readFiles(ctx context.Context, res *dbv1alpha1.AtlasSeed) ([]seedFile, map[string]string, error) {
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Name: res.Spec.ConfigMapRef.Name, Namespace: res.Namespace}, cm); err != nil {
+		return nil, nil, transient(fmt.Errorf("reading ConfigMap %q: %w", res.Spec.ConfigMapRef.Name, err))
+	}
+	var files []seedFile
+	for _, k := range slices.Sorted(maps.Keys(cm.Data)) {
+		f, err := parseSeedFile(k, cm.Data[k])
+		if err != nil {
+			return nil, nil, err
+		}
+		if f != nil {
+			files = append(files, *f)
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("ConfigMap %q has no seed files", cm.Name)
+	}
+	return files, cm.Data, nil
+}
+
+// parseSeedFile parses a key of the seed ConfigMap.
+func parseSeedFile(name, data string) (*seedFile, error) {
+	switch filepath.Ext(name) {
+	case ".sql":
+		stmts, err := migrate.NewLocalFile(name, []byte(data)).Stmts()
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", name, err)
+		}
+		return &seedFile{Name: name, Stmts: stmts}, nil
+	case ".yaml", ".yml", ".json":
+		b, err := yaml.YAMLToJSON([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", name, err)
+		}
+		rows := &seedRows{}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		// Keep numbers as written, to not lose the precision of large values.
+		dec.UseNumber()
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(rows); err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", name, err)
+		}
+		switch {
+		case rows.Table == "":
+			return nil, fmt.Errorf("%q: table is required", name)
+		case len(rows.Key) == 0:
+			return nil, fmt.Errorf("%q: key is required", name)
+		}
+		for i, row := range rows.Rows {
+			for _, k := range rows.Key {
+				if _, ok := row[k]; !ok {
+					return nil, fmt.Errorf("%q: row %d: missing key column %q", name, i, k)
+				}
+			}
+		}
+		return &seedFile{Name: name, Rows: rows}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// upsertStmts returns the statements that insert the rows, or update
+// them if a row with the same key already exists.
+func upsertStmts(driver string, rows *seedRows) ([]seedStmt, error) {
+	var (
+		quote   func(string) string
+		param   func(int) string
+		onDup   func(cols []string) string
+		keys    = make(map[string]bool, len(rows.Key))
+		quoteID = func(s string) string {
+			parts := strings.Split(s, ".")
+			for i := range parts {
+				parts[i] = quote(parts[i])
+			}
+			return strings.Join(parts, ".")
+		}
+	)
+	for _, k := range rows.Key {
+		keys[k] = true
+	}
+	switch driver {
+	case "mysql", "mariadb":
+		quote = func(s string) string { return "`" + strings.ReplaceAll(s, "`", "``") + "`" }
+		param = func(int) string { return "?" }
+		onDup = func(cols []string) string {
+			set := make([]string, 0, len(cols))
+			for _, c := range cols {
+				if !keys[c] {
+					set = append(set, fmt.Sprintf("%s = VALUES(%s)", quote(c), quote(c)))
+				}
+			}
+			// Rows made of keys only are left as is.
+			if len(set) == 0 {
+				set = append(set, fmt.Sprintf("%s = %s", quote(rows.Key[0]), quote(rows.Key[0])))
+			}
+			return "ON DUPLICATE KEY UPDATE " + strings.Join(set, ", ")
+		}
+	case "postgres":
+		quote = func(s string) string { return `"` + strings.ReplaceAll(s, `"`, `""`) + `"` }
+		param = func(i int) string { return "$" + strconv.Itoa(i) }
+		onDup = func(cols []string) string {
+			key := make([]string, len(rows.Key))
+			for i, k := range rows.Key {
+				key[i] = quote(k)
+			}
+			set := make([]string, 0, len(cols))
+			for _, c := range cols {
+				if !keys[c] {
+					set = append(set, fmt.Sprintf("%s = EXCLUDED.%s", quote(c), quote(c)))
+				}
+			}
+			if len(set) == 0 {
+				return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", strings.Join(key, ", "))
+			}
+			return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(key, ", "), strings.Join(set, ", "))
+		}
+	default:
+		return nil, fmt.Errorf("seeding rows is not supported for driver %q", driver)
+	}
+	stmts := make([]seedStmt, 0, len(rows.Rows))
+	for _, row := range rows.Rows {
+		var (
+			cols   = slices.Sorted(maps.Keys(row))
+			names  = make([]string, len(cols))
+			params = make([]string, len(cols))
+			args   = make([]any, len(cols))
+		)
+		for i, c := range cols {
+			v, err := seedValue(row[c])
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", c, err)
+			}
+			names[i], params[i], args[i] = quote(c), param(i+1), v
+		}
+		stmts = append(stmts, seedStmt{
+			query: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) %s",
+				quoteID(rows.Table), strings.Join(names, ", "), strings.Join(params, ", "), onDup(cols)),
+			args: args,
+		})
+	}
+	return stmts, nil
+}
+
+// seedValue converts a decoded value to a query argument. Numbers are passed
+// as written, and objects and arrays are passed as JSON documents.
+func seedValue(v any) (any, error) {
+	switch v := v.(type) {
+	case nil, string, bool:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+}
+
+// execSeed applies the seed files to the database in one transaction.
+// Failing to connect to the database is a transient error.
+func execSeed(ctx context.Context, u *url.URL, files []seedFile) (*seedResult, error) {
+	c, err := sqlclient.OpenURL(ctx, u)
+	if err != nil {
+		return nil, transient(err)
+	}
+	defer c.Close()
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, transient(err)
+	}
+	res := &seedResult{}
+	for _, f := range files {
+		stmts := make([]seedStmt, 0, len(f.Stmts))
+		for _, s := range f.Stmts {
+			stmts = append(stmts, seedStmt{query: s})
+		}
+		if f.Rows != nil {
+			if stmts, err = upsertStmts(c.Name, f.Rows); err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+		}
+		for _, s := range stmts {
+			if _, err := tx.ExecContext(ctx, s.query, s.args...); err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+		}
+		if f.Rows != nil {
+			res.Rows += len(stmts)
+		} else {
+			res.Statements += len(stmts)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// hashSeed returns the hash of the seed data and the state of its target.
+func hashSeed(t *seedTarget, data map[string]string) string {
+	h := sha256.New()
+	h.Write([]byte(t.url.Redacted() + "\x00" + t.hash + "\x00"))
+	for _, k := range slices.Sorted(maps.Keys(data)) {
+		h.Write([]byte(k + "\x00" + data[k] + "\x00"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// compareVersions compares two migration versions. Numeric versions
+// are compared by value, and the others are compared lexically.
+func compareVersions(a, b string) int {
+	x, err1 := strconv.ParseUint(a, 10, 64)
+	y, err2 := strconv.ParseUint(b, 10, 64)
+	if err1 == nil && err2 == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AtlasSeedReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dbv1alpha1.AtlasSeed{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.ConfigMap{}, r.configMapWatcher).
+		Watches(&dbv1alpha1.AtlasSchema{}, handler.EnqueueRequestsFromMapFunc(r.mapTarget)).
+		Watches(&dbv1alpha1.AtlasMigration{}, handler.EnqueueRequestsFromMapFunc(r.mapTarget)).
+		Complete(r)
+}
+
+// mapTarget returns the requests of the AtlasSeed resources referencing the target.
+func (r *AtlasSeedReconciler) mapTarget(ctx context.Context, o client.Object) []reconcile.Request {
+	list := &dbv1alpha1.AtlasSeedList{}
+	if err := r.List(ctx, list, client.InNamespace(o.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list AtlasSeed resources")
+		return nil
+	}
+	var reqs []reconcile.Request
+	for _, s := range list.Items {
+		var ref *corev1.LocalObjectReference
+		switch o.(type) {
+		case *dbv1alpha1.AtlasSchema:
+			ref = s.Spec.SchemaRef
+		case *dbv1alpha1.AtlasMigration:
+			ref = s.Spec.MigrationRef
+		}
+		if ref != nil && ref.Name == o.GetName() {
+			reqs = append(reqs, reconcile.Request{NamespacedName: s.NamespacedName()})
+		}
+	}
+	return reqs
+}
+
+func (r *AtlasSeedReconciler) recordErrEvent(res *dbv1alpha1.AtlasSeed, err error) {
+	reason := "Error"
+	if isTransient(err) {
+		reason = "TransientErr"
+	}
+	r.recorder.Event(res, corev1.EventTypeWarning, reason, strings.TrimSpace(err.Error()))
+}
@@ -15,10 +15,13 @@
 package controller
 
 import (
+	"bytes"
 	"context"
 	"embed"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os/exec"
 	"regexp"
 	"strings"
 	"text/template"
@@ -27,11 +30,14 @@ import (
 	"ariga.io/atlas-go-sdk/atlasexec"
 	"ariga.io/atlas/sql/migrate"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
 )
 
 const defaultEnvName = "kubernetes"
@@ -46,12 +52,18 @@ type (
 	AtlasExec interface {
 		// MigrateApply runs the `migrate apply` command and returns the successful runs.
 		MigrateApply(context.Context, *atlasexec.MigrateApplyParams) (*atlasexec.MigrateApply, error)
+		// MigrateDiff runs the `migrate diff` command.
+		MigrateDiff(context.Context, *MigrateDiffParams) error
 		// MigrateDown runs the `migrate down` command.
 		MigrateDown(context.Context, *atlasexec.MigrateDownParams) (*atlasexec.MigrateDown, error)
 		// MigrateLint runs the `migrate lint` command.
 		MigrateLint(context.Context, *atlasexec.MigrateLintParams) (*atlasexec.SummaryReport, error)
 		// MigrateStatus runs the `migrate status` command.
 		MigrateStatus(context.Context, *atlasexec.MigrateStatusParams) (*atlasexec.MigrateStatus, error)
+		// MigrateSet runs the `migrate set` command.
+		MigrateSet(context.Context, *MigrateSetParams) error
+		// MigrateTest runs the `migrate test` command.
+		MigrateTest(context.Context, *atlasexec.MigrateTestParams) (string, error)
 
 		// SchemaApply runs the `schema apply` command.
 		SchemaApply(context.Context, *atlasexec.SchemaApplyParams) (*atlasexec.SchemaApply, error)
@@ -63,20 +75,55 @@ type (
 		SchemaPlan(context.Context, *atlasexec.SchemaPlanParams) (*atlasexec.SchemaPlan, error)
 		// SchemaPlanList runs the `schema plan list` command.
 		SchemaPlanList(context.Context, *atlasexec.SchemaPlanListParams) ([]atlasexec.SchemaPlanFile, error)
+		// SchemaTest runs the `schema test` command.
+		SchemaTest(context.Context, *atlasexec.SchemaTestParams) (string, error)
 		// WhoAmI runs the `whoami` command.
 		WhoAmI(ctx context.Context) (*atlasexec.WhoAmI, error)
 	}
 	// AtlasExecFn is a function that returns an AtlasExec
 	// with the working directory.
 	AtlasExecFn func(string, *Cloud) (AtlasExec, error)
+	// MigrateSetParams are the parameters for the `migrate set` command.
+	MigrateSetParams struct {
+		Env     string
+		Version string
+	}
+	// MigrateDiffParams are the parameters for the `migrate diff` command.
+	MigrateDiffParams struct {
+		Env    string
+		DirURL string
+		To     string
+		Name   string
+	}
 	// Cloud holds the cloud configuration.
 	Cloud struct {
-		Token string
-		Repo  string
-		URL   string
+		Token   string
+		Repo    string
+		URL     string
+		Project string
 	}
 )
 
+type deployCtxKey struct{}
+
+// withDeployContext returns a new context with the given deployment context.
+func withDeployContext(ctx context.Context, rc *atlasexec.DeployRunContext) context.Context {
+	return context.WithValue(ctx, deployCtxKey{}, rc)
+}
+
+// deployContextFrom returns the deployment context from the given context, if any.
+func deployContextFrom(ctx context.Context) *atlasexec.DeployRunContext {
+	rc, _ := ctx.Value(deployCtxKey{}).(*atlasexec.DeployRunContext)
+	return rc
+}
+
+// triggerVersion returns the trigger version of the runs of the given resource. The run
+// context of the CLI has no field for the deployed resource, so its namespace and UID
+// are reported along with the operator version.
+func triggerVersion(ctx context.Context, o metav1.Object) string {
+	return strings.TrimSpace(fmt.Sprintf("%s (namespace=%s, uid=%s)", dbv1alpha1.VersionFromContext(ctx), o.GetNamespace(), o.GetUID()))
+}
+
 // NewAtlasExec returns a new AtlasExec with the given directory and cloud configuration.
 // The atlas binary is expected to be in the $PATH.
 func NewAtlasExec(dir string, c *Cloud) (AtlasExec, error) {
@@ -84,14 +131,142 @@ func NewAtlasExec(dir string, c *Cloud) (AtlasExec, error) {
 	if err != nil {
 		return nil, err
 	}
+	env := atlasexec.NewOSEnviron()
 	if c != nil && c.Token != "" {
-		env := atlasexec.NewOSEnviron()
 		env["ATLAS_TOKEN"] = c.Token
 		if err = cli.SetEnv(env); err != nil {
 			return nil, err
 		}
 	}
-	return cli, nil
+	return &atlasExec{Client: cli, dir: dir, env: env}, nil
+}
+
+// atlasExec extends the atlasexec.Client with the
+// commands that are not supported by the SDK yet.
+type atlasExec struct {
+	*atlasexec.Client
+	dir string
+	env atlasexec.Environ
+}
+
+// MigrateSet implements AtlasExec.
+func (c *atlasExec) MigrateSet(ctx context.Context, params *MigrateSetParams) error {
+	args := []string{"migrate", "set"}
+	if params.Env != "" {
+		args = append(args, "--env", params.Env)
+	}
+	args = append(args, params.Version)
+	_, err := c.run(ctx, args...)
+	return err
+}
+
+// MigrateDiff implements AtlasExec.
+func (c *atlasExec) MigrateDiff(ctx context.Context, params *MigrateDiffParams) error {
+	args := []string{"migrate", "diff"}
+	if params.Env != "" {
+		args = append(args, "--env", params.Env)
+	}
+	if params.DirURL != "" {
+		args = append(args, "--dir", params.DirURL)
+	}
+	if params.To != "" {
+		args = append(args, "--to", params.To)
+	}
+	if params.Name != "" {
+		args = append(args, params.Name)
+	}
+	_, err := c.run(ctx, args...)
+	return err
+}
+
+// SchemaApply implements AtlasExec. The SDK does not support passing the deployment
+// context to the `schema apply` command, so the command is executed directly if the
+// context holds one.
+func (c *atlasExec) SchemaApply(ctx context.Context, params *atlasexec.SchemaApplyParams) (*atlasexec.SchemaApply, error) {
+	rc := deployContextFrom(ctx)
+	if rc == nil {
+		return c.Client.SchemaApply(ctx, params)
+	}
+	runCtx, err := json.Marshal(rc)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"schema", "apply", "--format", "{{ json . }}", "--context", string(runCtx)}
+	if params.ConfigURL != "" {
+		args = append(args, "--config", params.ConfigURL)
+	}
+	if params.Env != "" {
+		args = append(args, "--env", params.Env)
+	}
+	if params.Vars != nil {
+		args = append(args, params.Vars.AsArgs()...)
+	}
+	if params.URL != "" {
+		args = append(args, "--url", params.URL)
+	}
+	if params.To != "" {
+		args = append(args, "--to", params.To)
+	}
+	if params.TxMode != "" {
+		args = append(args, "--tx-mode", params.TxMode)
+	}
+	if params.DevURL != "" {
+		args = append(args, "--dev-url", params.DevURL)
+	}
+	if len(params.Schema) > 0 {
+		args = append(args, "--schema", strings.Join(params.Schema, ","))
+	}
+	if len(params.Exclude) > 0 {
+		args = append(args, "--exclude", strings.Join(params.Exclude, ","))
+	}
+	if params.PlanURL != "" {
+		args = append(args, "--plan", params.PlanURL)
+	}
+	switch {
+	case params.DryRun:
+		args = append(args, "--dry-run")
+	case params.AutoApprove:
+		args = append(args, "--auto-approve")
+	}
+	out, err := c.run(ctx, args...)
+	if err != nil {
+		// Report the result of the failed attempt, if printed by the CLI.
+		if e := (*atlasexec.Error)(nil); errors.As(err, &e) && e.Stdout != "" {
+			r := &atlasexec.SchemaApply{}
+			if json.NewDecoder(strings.NewReader(e.Stdout)).Decode(r) == nil {
+				return nil, &atlasexec.SchemaApplyError{Result: []*atlasexec.SchemaApply{r}, Stderr: e.Stderr}
+			}
+		}
+		return nil, err
+	}
+	r := &atlasexec.SchemaApply{}
+	if err := json.NewDecoder(strings.NewReader(out)).Decode(r); err != nil {
+		return nil, fmt.Errorf("decoding JSON from stdout: %w", err)
+	}
+	return r, nil
+}
+
+// run executes the atlas binary with the given arguments
+// and returns its standard output.
+func (c *atlasExec) run(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "atlas", args...)
+	cmd.Dir = c.dir
+	cmd.Env = append(c.env.ToSlice(),
+		"ATLAS_NO_UPDATE_NOTIFIER=1",
+		"ATLAS_NO_UPGRADE_SUGGESTIONS=1",
+	)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() == 0 && stdout.Len() == 0 {
+			return "", err
+		}
+		return "", &atlasexec.Error{
+			Stderr: strings.TrimSpace(stderr.String()),
+			Stdout: strings.TrimSpace(stdout.String()),
+		}
+	}
+	return stdout.String(), nil
 }
 
 var (
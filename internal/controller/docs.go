@@ -0,0 +1,255 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+type (
+	// inspectedRealm is the JSON representation of
+	// an inspected database, as reported by Atlas.
+	inspectedRealm struct {
+		Schemas []inspectedSchema `json:"schemas"`
+	}
+	inspectedSchema struct {
+		Name   string           `json:"name"`
+		Tables []inspectedTable `json:"tables"`
+	}
+	inspectedTable struct {
+		Name        string           `json:"name"`
+		Columns     []inspectedCol   `json:"columns"`
+		PrimaryKey  *inspectedIndex  `json:"primary_key,omitempty"`
+		Indexes     []inspectedIndex `json:"indexes,omitempty"`
+		ForeignKeys []inspectedFK    `json:"foreign_keys,omitempty"`
+	}
+	inspectedCol struct {
+		Name string `json:"name"`
+		Type string `json:"type,omitempty"`
+		Null bool   `json:"null,omitempty"`
+	}
+	inspectedIndex struct {
+		Name   string `json:"name,omitempty"`
+		Unique bool   `json:"unique,omitempty"`
+		Parts  []struct {
+			Column string `json:"column,omitempty"`
+			Func   string `json:"func,omitempty"`
+		} `json:"parts,omitempty"`
+	}
+	inspectedFK struct {
+		Name       string   `json:"name"`
+		Columns    []string `json:"columns,omitempty"`
+		References struct {
+			Table   string   `json:"table"`
+			Columns []string `json:"columns,omitempty"`
+		} `json:"references"`
+	}
+)
+
+// Keys of the documentation ConfigMap.
+const (
+	docsMarkdownKey = "schema.md"
+	docsMermaidKey  = "erd.mmd"
+)
+
+// writeDocs writes the documentation of the schema, if enabled. Failing to
+// write the documentation does not fail the reconciliation, but is reported.
+func (r *AtlasSchemaReconciler) writeDocs(ctx context.Context, res *dbv1alpha1.AtlasSchema, cli AtlasExec, data *managedData) {
+	if res.Spec.Docs == nil {
+		return
+	}
+	if err := r.docsConfigMap(ctx, res, cli, data); err != nil {
+		log.FromContext(ctx).Error(err, "failed to write the schema documentation")
+		r.recorder.Event(res, corev1.EventTypeWarning, "GeneratingDocs", err.Error())
+	}
+}
+
+// docsConfigMap inspects the target database and writes the documentation
+// of its schema to a ConfigMap owned by the resource.
+func (r *AtlasSchemaReconciler) docsConfigMap(ctx context.Context, res *dbv1alpha1.AtlasSchema, cli AtlasExec, data *managedData) error {
+	js, err := cli.SchemaInspect(ctx, &atlasexec.SchemaInspectParams{
+		Env:    data.EnvName,
+		Format: "{{ json . }}",
+	})
+	if err != nil {
+		return err
+	}
+	docs, err := renderDocs(js)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(js))
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      res.DocsConfigMapName(),
+			Namespace: res.Namespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r, cm, func() error {
+		if cm.Annotations == nil {
+			cm.Annotations = make(map[string]string)
+		}
+		cm.Annotations[dbv1alpha1.AnnotationSchemaHash] = hex.EncodeToString(sum[:])
+		cm.Annotations[dbv1alpha1.AnnotationInspectedAt] = time.Now().UTC().Format(time.RFC3339)
+		cm.Data = docs
+		return controllerutil.SetControllerReference(res, cm, r.scheme)
+	})
+	return err
+}
+
+// renderDocs renders the JSON representation of an inspected database
+// into a Markdown documentation and a Mermaid ER diagram.
+func renderDocs(js string) (map[string]string, error) {
+	var realm inspectedRealm
+	if err := json.Unmarshal([]byte(js), &realm); err != nil {
+		return nil, fmt.Errorf("decoding the inspected schema: %w", err)
+	}
+	erd := renderMermaid(realm)
+	var md strings.Builder
+	md.WriteString("# Database schema\n\n```mermaid\n" + erd + "```\n")
+	for _, s := range realm.Schemas {
+		fmt.Fprintf(&md, "\n## Schema `%s`\n", s.Name)
+		for _, t := range s.Tables {
+			fmt.Fprintf(&md, "\n### Table `%s`\n\n", t.Name)
+			md.WriteString("| Column | Type | Null | Key |\n|--------|------|------|-----|\n")
+			keys := columnKeys(t)
+			for _, c := range t.Columns {
+				null := "NO"
+				if c.Null {
+					null = "YES"
+				}
+				fmt.Fprintf(&md, "| %s | %s | %s | %s |\n", c.Name, c.Type, null, strings.Join(keys[c.Name], ", "))
+			}
+			if len(t.Indexes) > 0 {
+				md.WriteString("\nIndexes:\n")
+				for _, idx := range t.Indexes {
+					kind := ""
+					if idx.Unique {
+						kind = " (unique)"
+					}
+					fmt.Fprintf(&md, "- `%s`%s: %s\n", idx.Name, kind, strings.Join(indexParts(idx), ", "))
+				}
+			}
+			if len(t.ForeignKeys) > 0 {
+				md.WriteString("\nForeign keys:\n")
+				for _, fk := range t.ForeignKeys {
+					fmt.Fprintf(&md, "- `%s`: (%s) references `%s` (%s)\n", fk.Name,
+						strings.Join(fk.Columns, ", "), fk.References.Table, strings.Join(fk.References.Columns, ", "))
+				}
+			}
+		}
+	}
+	return map[string]string{
+		docsMarkdownKey: md.String(),
+		docsMermaidKey:  erd,
+	}, nil
+}
+
+// nonIdent matches the characters that are not allowed in Mermaid identifiers.
+var nonIdent = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// renderMermaid renders the ER diagram of the given database. Tables are qualified
+// with their schema names only if the database contains more than one schema.
+func renderMermaid(realm inspectedRealm) string {
+	ident := func(s, t string) string {
+		if len(realm.Schemas) > 1 && !strings.Contains(t, ".") {
+			t = s + "_" + t
+		}
+		return strings.Trim(nonIdent.ReplaceAllString(t, "_"), "_")
+	}
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+	for _, s := range realm.Schemas {
+		for _, t := range s.Tables {
+			fmt.Fprintf(&b, "  %s {\n", ident(s.Name, t.Name))
+			keys := columnKeys(t)
+			for _, c := range t.Columns {
+				typ := strings.Trim(nonIdent.ReplaceAllString(c.Type, "_"), "_")
+				if typ == "" {
+					typ = "unknown"
+				}
+				fmt.Fprintf(&b, "    %s %s", typ, strings.Trim(nonIdent.ReplaceAllString(c.Name, "_"), "_"))
+				if k := keys[c.Name]; len(k) > 0 {
+					fmt.Fprintf(&b, " %s", strings.Join(k, ","))
+				}
+				b.WriteString("\n")
+			}
+			b.WriteString("  }\n")
+		}
+	}
+	for _, s := range realm.Schemas {
+		for _, t := range s.Tables {
+			for _, fk := range t.ForeignKeys {
+				fmt.Fprintf(&b, "  %s ||--o{ %s : %q\n", ident(s.Name, fk.References.Table), ident(s.Name, t.Name), fk.Name)
+			}
+		}
+	}
+	return b.String()
+}
+
+// columnKeys returns the keys (PK, FK, UK) each column of the table is part of.
+func columnKeys(t inspectedTable) map[string][]string {
+	keys := make(map[string][]string)
+	add := func(c, k string) {
+		if !slices.Contains(keys[c], k) {
+			keys[c] = append(keys[c], k)
+		}
+	}
+	if pk := t.PrimaryKey; pk != nil {
+		for _, p := range pk.Parts {
+			add(p.Column, "PK")
+		}
+	}
+	for _, fk := range t.ForeignKeys {
+		for _, c := range fk.Columns {
+			add(c, "FK")
+		}
+	}
+	for _, idx := range t.Indexes {
+		if idx.Unique && len(idx.Parts) == 1 && idx.Parts[0].Column != "" {
+			add(idx.Parts[0].Column, "UK")
+		}
+	}
+	return keys
+}
+
+// indexParts returns the columns or expressions of the index.
+func indexParts(idx inspectedIndex) []string {
+	parts := make([]string, 0, len(idx.Parts))
+	for _, p := range idx.Parts {
+		if p.Column != "" {
+			parts = append(parts, p.Column)
+		} else {
+			parts = append(parts, p.Func)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,111 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+type (
+	// fanout manages the child resources of a parent resource, one for each
+	// of its items: the targets of a set, or the tenants of a database.
+	fanout struct {
+		client.Client
+		scheme   *runtime.Scheme
+		recorder record.EventRecorder
+		// kind of the child resources, e.g. AtlasSchema.
+		kind string
+		// noun describing an item in the events, e.g. target.
+		noun string
+	}
+	// fanoutItem is an item of a fanout, and its child resource.
+	fanoutItem struct {
+		// name of the item, reported in the events and the status.
+		name string
+		// child is the child resource, with its name and namespace set.
+		child client.Object
+		// mutate sets the desired state of the child resource.
+		mutate func()
+		// status returns the state of the item, once the child is created or updated.
+		status func() dbv1alpha1.SetTargetStatus
+	}
+)
+
+// errNotControlled is returned when a child resource exists, but is not controlled by the parent.
+var errNotControlled = errors.New("not controlled by the parent")
+
+// reconcile creates or updates the child resources of the items, and deletes the children
+// of the removed items, listed with the given options. Existing resources that are not
+// controlled by the parent are never adopted. It returns the states of the items.
+func (f *fanout) reconcile(ctx context.Context, parent client.Object, items []fanoutItem, list client.ObjectList, opts ...client.ListOption) ([]dbv1alpha1.SetTargetStatus, error) {
+	var (
+		statuses = make([]dbv1alpha1.SetTargetStatus, 0, len(items))
+		keep     = make(map[string]bool, len(items))
+	)
+	for _, it := range items {
+		name := it.child.GetName()
+		if keep[name] {
+			return nil, fmt.Errorf("%s %q maps to the resource name %q of another %s", f.noun, it.name, name, f.noun)
+		}
+		op, err := controllerutil.CreateOrUpdate(ctx, f, it.child, func() error {
+			if it.child.GetResourceVersion() != "" && !metav1.IsControlledBy(it.child, parent) {
+				return errNotControlled
+			}
+			it.mutate()
+			return controllerutil.SetControllerReference(parent, it.child, f.scheme)
+		})
+		switch {
+		case errors.Is(err, errNotControlled):
+			return nil, fmt.Errorf("%s %q of %s %q already exists and is not controlled by this resource", f.kind, name, f.noun, it.name)
+		case err != nil:
+			return nil, transient(err)
+		case op == controllerutil.OperationResultCreated:
+			f.recorder.Eventf(parent, corev1.EventTypeNormal, "Created", "Created %s %q for %s %q", f.kind, name, f.noun, it.name)
+		}
+		keep[name] = true
+		statuses = append(statuses, it.status())
+	}
+	// Garbage-collect the children of the removed items.
+	if err := f.List(ctx, list, opts...); err != nil {
+		return nil, transient(err)
+	}
+	children, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range children {
+		c, ok := o.(client.Object)
+		if !ok || keep[c.GetName()] || !metav1.IsControlledBy(c, parent) {
+			continue
+		}
+		if err := f.Delete(ctx, c); client.IgnoreNotFound(err) != nil {
+			return nil, transient(err)
+		}
+		f.recorder.Eventf(parent, corev1.EventTypeNormal, "Deleted", "Deleted %s %q of a removed %s", f.kind, c.GetName(), f.noun)
+	}
+	return statuses, nil
+}
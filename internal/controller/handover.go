@@ -0,0 +1,124 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+// handover takes over the target database from the AtlasSchema referenced by the resource.
+// The AtlasSchema must be in sync with the database, and the database must match the schema
+// of the baseline version, which defaults to the first version of the migration directory.
+// Then, the AtlasSchema is released, and the baseline version is returned.
+//
+// It returns a handoverErr if the database cannot be handed over.
+func (r *AtlasMigrationReconciler) handover(ctx context.Context, cli AtlasExec, data *migrationData, res *dbv1alpha1.AtlasMigration, status *atlasexec.MigrateStatus) (string, error) {
+	log := ctrl.Log.WithName("atlas_migration.handover")
+	name := res.Spec.HandoverFrom.Name
+	sc := &dbv1alpha1.AtlasSchema{}
+	switch err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: res.Namespace}, sc); {
+	case apierrors.IsNotFound(err):
+		return "", &handoverErr{
+			reason: "HandoverSchemaNotFound",
+			msg:    fmt.Sprintf("the AtlasSchema %q to take over the database from was not found", name),
+		}
+	case err != nil:
+		return "", err
+	}
+	switch to := sc.Annotations[dbv1alpha1.AnnotationReleasedTo]; {
+	case to == res.Name:
+		// Released by a previous attempt, the database was not migrated yet.
+	case to != "":
+		return "", &handoverErr{
+			reason: "HandoverConflict",
+			msg:    fmt.Sprintf("the AtlasSchema %q was already handed over to AtlasMigration %q", name, to),
+		}
+	case !sc.IsReady():
+		return "", &handoverErr{
+			reason: "HandoverSchemaNotReady",
+			msg:    fmt.Sprintf("the AtlasSchema %q is not in sync with the database, waiting for it to be ready", name),
+			retry:  true,
+		}
+	}
+	if len(status.Available) == 0 {
+		return "", &handoverErr{
+			reason: "HandoverMismatch",
+			msg:    "the migration directory is empty, it must hold the baseline version of the database",
+		}
+	}
+	baseline := cmp.Or(data.Baseline, status.Available[0].Version)
+	hash := func(u string) (string, error) {
+		return cli.SchemaInspect(ctx, &atlasexec.SchemaInspectParams{
+			Env:    data.EnvName,
+			URL:    u,
+			Format: `{{ .Hash | base64url }}`,
+		})
+	}
+	// An empty URL inspects the target database of the env.
+	live, err := hash("")
+	if err != nil {
+		return "", err
+	}
+	h, err := hash(fmt.Sprintf("%s?version=%s", data.DirURL(), baseline))
+	if err != nil {
+		return "", err
+	}
+	if h != live {
+		return "", &handoverErr{
+			reason: "HandoverMismatch",
+			msg:    fmt.Sprintf("the schema of the target database does not match the baseline version %s of the migration directory", baseline),
+		}
+	}
+	if sc.Annotations[dbv1alpha1.AnnotationReleasedTo] == "" {
+		patch := client.MergeFrom(sc.DeepCopy())
+		if sc.Annotations == nil {
+			sc.Annotations = make(map[string]string)
+		}
+		sc.Annotations[dbv1alpha1.AnnotationReleasedTo] = res.Name
+		if err := r.Patch(ctx, sc, patch); err != nil {
+			return "", err
+		}
+		log.Info("released the AtlasSchema", "schema", name, "baseline", baseline)
+	}
+	return baseline, nil
+}
+
+// handoverErr is returned when the database cannot be handed over from an AtlasSchema.
+type handoverErr struct {
+	reason string
+	msg    string
+	// retry reports if the handover is retried without a change to the resource.
+	retry bool
+}
+
+// Error implements the error interface
+func (e *handoverErr) Error() string {
+	return e.msg
+}
+
+// Reason returns the reason of the error
+func (e *handoverErr) Reason() string {
+	return e.reason
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+func TestMigration_Handover(t *testing.T) {
+	var (
+		meta = migrationObjmeta()
+		obj  = &dbv1alpha1.AtlasMigration{
+			ObjectMeta: meta,
+			Spec: dbv1alpha1.AtlasMigrationSpec{
+				TargetSpec: dbv1alpha1.TargetSpec{
+					URL: "sqlite://file?mode=memory",
+				},
+				HandoverFrom: &corev1.LocalObjectReference{Name: "app"},
+				Dir: dbv1alpha1.Dir{
+					Local: map[string]string{
+						"1.sql": "CREATE TABLE t1 (id INT);",
+						"2.sql": "CREATE TABLE t2 (id INT);",
+					},
+				},
+			},
+			Status: dbv1alpha1.AtlasMigrationStatus{
+				Conditions: []metav1.Condition{
+					{Type: "Ready", Status: metav1.ConditionFalse},
+				},
+			},
+		}
+		sc = &dbv1alpha1.AtlasSchema{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: meta.Namespace},
+			Status: dbv1alpha1.AtlasSchemaStatus{
+				Conditions: []metav1.Condition{
+					{Type: "Ready", Status: metav1.ConditionFalse},
+				},
+			},
+		}
+		hashes = map[string]string{
+			"":                            "live",
+			"file://migrations?version=1": "v1",
+		}
+	)
+	mockExec := &mockAtlasExec{}
+	mockExec.inspectFn = func(p *atlasexec.SchemaInspectParams) (string, error) {
+		return hashes[p.URL], nil
+	}
+	mockExec.status.res = &atlasexec.MigrateStatus{
+		Available: []atlasexec.File{{Version: "1"}, {Version: "2"}},
+		Pending:   []atlasexec.File{{Version: "2"}},
+	}
+	mockExec.apply.res = &atlasexec.MigrateApply{Target: "2"}
+	h, reconcile := newRunner(NewAtlasMigrationReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(obj, sc)
+		cb.WithObjects(obj, sc)
+	}, mockExec)
+	assert := func(after time.Duration, reason, msg string) {
+		t.Helper()
+		reconcile(obj, func(result ctrl.Result, err error) {
+			require.NoError(t, err)
+			require.Equal(t, after, result.RequeueAfter)
+			res := &dbv1alpha1.AtlasMigration{ObjectMeta: meta}
+			h.get(t, res)
+			require.Equal(t, reason, res.Status.Conditions[0].Reason)
+			require.Contains(t, res.Status.Conditions[0].Message, msg)
+		})
+	}
+	// The AtlasSchema is not in sync with the database yet.
+	assert(5*time.Second, "HandoverSchemaNotReady", `the AtlasSchema "app" is not in sync with the database`)
+
+	// The database does not match the first version.
+	sc.Status.Conditions[0].Status = metav1.ConditionTrue
+	require.NoError(t, h.client.Status().Update(context.Background(), sc))
+	assert(0, "HandoverMismatch", "does not match the baseline version 1 of the migration directory")
+	h.get(t, sc)
+	require.Empty(t, sc.Annotations[dbv1alpha1.AnnotationReleasedTo])
+	h.events()
+
+	// The AtlasSchema is released, and the database is baselined.
+	hashes["file://migrations?version=1"] = "live"
+	assert(0, "Applied", "")
+	h.get(t, sc)
+	require.Equal(t, meta.Name, sc.Annotations[dbv1alpha1.AnnotationReleasedTo])
+	require.Contains(t, h.events(), `Normal HandedOver Took over the database from AtlasSchema "app" with baseline version 1`)
+}
+
+func TestMigration_HandoverConflict(t *testing.T) {
+	meta := migrationObjmeta()
+	obj := &dbv1alpha1.AtlasMigration{
+		ObjectMeta: meta,
+		Spec: dbv1alpha1.AtlasMigrationSpec{
+			TargetSpec:   dbv1alpha1.TargetSpec{URL: "sqlite://file?mode=memory"},
+			HandoverFrom: &corev1.LocalObjectReference{Name: "app"},
+			Dir: dbv1alpha1.Dir{
+				Local: map[string]string{"1.sql": "CREATE TABLE t1 (id INT);"},
+			},
+		},
+		Status: dbv1alpha1.AtlasMigrationStatus{
+			Conditions: []metav1.Condition{
+				{Type: "Ready", Status: metav1.ConditionFalse},
+			},
+		},
+	}
+	sc := &dbv1alpha1.AtlasSchema{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app",
+			Namespace:   meta.Namespace,
+			Annotations: map[string]string{dbv1alpha1.AnnotationReleasedTo: "other"},
+		},
+	}
+	mockExec := &mockAtlasExec{}
+	mockExec.status.res = &atlasexec.MigrateStatus{
+		Available: []atlasexec.File{{Version: "1"}},
+	}
+	h, reconcile := newRunner(NewAtlasMigrationReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(obj)
+		cb.WithObjects(obj, sc)
+	}, mockExec)
+	reconcile(obj, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+		require.EqualValues(t, ctrl.Result{}, result)
+	})
+	res := &dbv1alpha1.AtlasMigration{ObjectMeta: meta}
+	h.get(t, res)
+	require.Equal(t, "HandoverConflict", res.Status.Conditions[0].Reason)
+	require.Equal(t, `the AtlasSchema "app" was already handed over to AtlasMigration "other"`, res.Status.Conditions[0].Message)
+}
+
+func TestReconcile_Released(t *testing.T) {
+	meta := objmeta()
+	meta.Annotations = map[string]string{dbv1alpha1.AnnotationReleasedTo: "app-migrations"}
+	obj := &dbv1alpha1.AtlasSchema{
+		ObjectMeta: meta,
+		Spec: dbv1alpha1.AtlasSchemaSpec{
+			TargetSpec: dbv1alpha1.TargetSpec{URL: "sqlite://file2/?mode=memory"},
+			Schema:     dbv1alpha1.Schema{SQL: "CREATE TABLE foo(id INT PRIMARY KEY);"},
+		},
+		Status: dbv1alpha1.AtlasSchemaStatus{
+			Conditions: []metav1.Condition{
+				{Type: "Ready", Status: metav1.ConditionTrue},
+			},
+		},
+	}
+	mockExec := &mockAtlasExec{}
+	h, reconcile := newRunner(NewAtlasSchemaReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(obj)
+		cb.WithObjects(obj)
+	}, mockExec)
+	reconcile(obj, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+		require.EqualValues(t, ctrl.Result{}, result)
+	})
+	res := &dbv1alpha1.AtlasSchema{ObjectMeta: meta}
+	h.get(t, res)
+	require.False(t, res.IsReady())
+	require.Equal(t, "Released", res.Status.Conditions[0].Reason)
+	require.Equal(t, `the database is managed by AtlasMigration "app-migrations"`, res.Status.Conditions[0].Message)
+	require.Nil(t, mockExec.applyParams, "released resources must not be applied")
+}
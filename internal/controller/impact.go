@@ -0,0 +1,183 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	"ariga.io/atlas/sql/sqlclient"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+
+	_ "ariga.io/atlas/sql/mysql"
+	_ "ariga.io/atlas/sql/postgres"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+type (
+	// tableName is a table touched by a planned statement.
+	// Schema is empty if the statement does not qualify the table.
+	tableName struct {
+		Schema, Name string
+	}
+	// tableStatsFn returns the live statistics of the given tables in the target database.
+	tableStatsFn func(context.Context, *url.URL, []tableName) ([]dbv1alpha1.TableImpact, error)
+	// impactErr is returned when the planned changes exceed the impact thresholds.
+	impactErr struct {
+		impact []dbv1alpha1.TableImpact
+		exceed []string
+	}
+)
+
+func (e *impactErr) Error() string {
+	var buf strings.Builder
+	buf.WriteString("planned changes exceed the impact thresholds:\n")
+	for _, x := range e.exceed {
+		buf.WriteString("- " + x + "\n")
+	}
+	return buf.String()
+}
+
+// Reason implements the interface used for reporting events.
+func (e *impactErr) Reason() string {
+	return "ImpactThresholdExceeded"
+}
+
+// impact runs the impact analysis of the changes planned for the target database.
+// It returns the statistics of the touched tables, and an impactErr if any of the
+// thresholds is exceeded.
+func (r *AtlasSchemaReconciler) impact(ctx context.Context, cli AtlasExec, data *managedData) ([]dbv1alpha1.TableImpact, error) {
+	plan, err := cli.SchemaApply(ctx, &atlasexec.SchemaApplyParams{
+		Env:    data.EnvName,
+		To:     data.Desired.String(),
+		DryRun: true, // Dry run to get pending changes.
+	})
+	if err != nil {
+		return nil, err
+	}
+	tables := touchedTables(plan.Changes.Pending)
+	if len(tables) == 0 {
+		return nil, nil
+	}
+	stats, err := r.tableStats(ctx, data.URL, tables)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkImpact(stats, data.Policy.Impact); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// checkImpact checks the statistics of the touched tables against the thresholds.
+func checkImpact(stats []dbv1alpha1.TableImpact, p *dbv1alpha1.Impact) error {
+	var (
+		rows int64
+		err  = &impactErr{impact: stats}
+	)
+	for _, s := range stats {
+		rows += s.Rows
+		if p.MaxTableBytes > 0 && s.Bytes > p.MaxTableBytes {
+			err.exceed = append(err.exceed, fmt.Sprintf("table %s is estimated at %d bytes, maxTableBytes is %d", s.Table, s.Bytes, p.MaxTableBytes))
+		}
+	}
+	if p.MaxRowsRewritten > 0 && rows > p.MaxRowsRewritten {
+		err.exceed = append(err.exceed, fmt.Sprintf("planned changes touch an estimated %d rows, maxRowsRewritten is %d", rows, p.MaxRowsRewritten))
+	}
+	if len(err.exceed) > 0 {
+		return err
+	}
+	return nil
+}
+
+// tableStmt matches the statements that scan, rewrite or drop a table,
+// and captures the (optionally qualified) name of the table.
+var tableStmt = regexp.MustCompile("(?is)^\\s*(?:ALTER\\s+TABLE|DROP\\s+TABLE|TRUNCATE(?:\\s+TABLE)?|CREATE\\s+(?:UNIQUE\\s+)?INDEX\\s+.*?\\s+ON)" +
+	"\\s+(?:IF\\s+EXISTS\\s+)?(?:ONLY\\s+)?((?:[`\"]?[\\w$]+[`\"]?\\.)?[`\"]?[\\w$]+[`\"]?)")
+
+// touchedTables returns the tables touched by the given statements, in order of appearance.
+func touchedTables(stmts []string) []tableName {
+	var (
+		tables []tableName
+		seen   = make(map[tableName]bool)
+	)
+	for _, s := range stmts {
+		m := tableStmt.FindStringSubmatch(s)
+		if m == nil {
+			continue
+		}
+		var t tableName
+		parts := strings.SplitN(m[1], ".", 2)
+		if len(parts) == 2 {
+			t.Schema, parts = strings.Trim(parts[0], "`\""), parts[1:]
+		}
+		t.Name = strings.Trim(parts[0], "`\"")
+		if !seen[t] {
+			seen[t] = true
+			tables = append(tables, t)
+		}
+	}
+	return tables
+}
+
+// queryTableStats queries the estimated number of rows and size of the given
+// tables from the catalog of the target database. Only MySQL, MariaDB and
+// PostgreSQL are supported.
+func queryTableStats(ctx context.Context, u *url.URL, tables []tableName) ([]dbv1alpha1.TableImpact, error) {
+	c, err := sqlclient.OpenURL(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	var query string
+	switch c.Name {
+	case "mysql", "mariadb":
+		query = "SELECT TABLE_SCHEMA, COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH + INDEX_LENGTH, 0) FROM information_schema.TABLES " +
+			"WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?"
+	case "postgres":
+		query = "SELECT n.nspname, GREATEST(c.reltuples, 0)::bigint, pg_total_relation_size(c.oid) FROM pg_class c " +
+			"JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = COALESCE(NULLIF($1, ''), current_schema()) AND c.relname = $2"
+	default:
+		return nil, fmt.Errorf("impact analysis is not supported for driver %q", c.Name)
+	}
+	stats := make([]dbv1alpha1.TableImpact, 0, len(tables))
+	for _, t := range tables {
+		var (
+			s      dbv1alpha1.TableImpact
+			schema = t.Schema
+		)
+		if schema == "" {
+			schema = c.URL.Schema
+		}
+		switch err := c.DB.QueryRowContext(ctx, query, schema, t.Name).Scan(&schema, &s.Rows, &s.Bytes); {
+		case errors.Is(err, sql.ErrNoRows):
+			// The table is created by the planned changes.
+			continue
+		case err != nil:
+			return nil, err
+		}
+		s.Table = schema + "." + t.Name
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+// inspect inspects the target database, in inspect mode, and writes its
+// schema in HCL and SQL formats to a ConfigMap owned by the resource.
+// The target database is inspected again after the configured interval.
+func (r *AtlasSchemaReconciler) inspect(ctx context.Context, res *dbv1alpha1.AtlasSchema, data *managedData) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	wd, err := atlasexec.NewWorkingDir(atlasexec.WithAtlasHCL(data.render))
+	if err != nil {
+		res.SetNotReady("CreatingWorkingDir", err.Error())
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	defer wd.Close()
+	cli, err := r.atlasClient(wd.Path(), data.Cloud)
+	if err != nil {
+		res.SetNotReady("CreatingAtlasClient", err.Error())
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	formats := map[string]string{
+		"schema.hcl": "", // Defaults to HCL.
+		"schema.sql": "{{ sql . }}",
+	}
+	cmData := make(map[string]string, len(formats))
+	for key, format := range formats {
+		cmData[key], err = cli.SchemaInspect(ctx, &atlasexec.SchemaInspectParams{
+			Env:    data.EnvName,
+			Format: format,
+		})
+		if err != nil {
+			res.SetNotReady("InspectingSchema", err.Error())
+			r.recorder.Event(res, corev1.EventTypeWarning, "InspectingSchema", err.Error())
+			if !isSQLErr(err) {
+				err = transient(err)
+			}
+			r.recordErrEvent(res, err)
+			return result(err)
+		}
+	}
+	sum := sha256.Sum256([]byte(cmData["schema.hcl"]))
+	hash := hex.EncodeToString(sum[:])
+	now := time.Now()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      res.InspectConfigMapName(),
+			Namespace: res.Namespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r, cm, func() error {
+		if cm.Annotations == nil {
+			cm.Annotations = make(map[string]string)
+		}
+		cm.Annotations[dbv1alpha1.AnnotationSchemaHash] = hash
+		cm.Annotations[dbv1alpha1.AnnotationInspectedAt] = now.UTC().Format(time.RFC3339)
+		cm.Data = cmData
+		return controllerutil.SetControllerReference(res, cm, r.scheme)
+	})
+	if err != nil {
+		res.SetNotReady("WritingConfigMap", err.Error())
+		r.recordErrEvent(res, err)
+		return result(transient(err))
+	}
+	if hash != res.Status.ObservedHash {
+		log.Info("inspected schema was changed", "configmap", cm.Name, "hash", hash)
+		r.recorder.Eventf(res, corev1.EventTypeNormal, "Inspected", "Schema exported to configmap %s", cm.Name)
+	}
+	res.SetInspected(dbv1alpha1.AtlasSchemaStatus{
+		ObservedHash:  hash,
+		LastInspected: now.Unix(),
+	}, fmt.Sprintf("The schema has been exported to configmap %s", cm.Name))
+	r.writeDocs(ctx, res, cli, data)
+	interval := time.Hour
+	if i := res.Spec.Inspect; i != nil && i.Interval.Duration > 0 {
+		interval = i.Interval.Duration
+	}
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
@@ -16,28 +16,32 @@ package controller
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"strings"
 
 	"ariga.io/atlas-go-sdk/atlasexec"
-	"ariga.io/atlas/sql/sqlcheck"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
 )
 
 const lintDirName = "lint-migrations"
 
-// lint run `atlas migrate lint` to check for destructive changes.
-// It returns a destructiveErr if destructive changes are detected.
+// lint run `atlas migrate lint` to check the pending changes against the
+// analyzers configured in the linting policy. It returns all diagnostics
+// reported by Atlas, and a lintErr if any of them is at the error level.
 //
 // It works by creating two versions of migration:
 // - 1.sql: the current schema.
 // - 2.sql: the pending changes.
 // Then it runs `atlas migrate lint` in the temporary directory.
-func (r *AtlasSchemaReconciler) lint(ctx context.Context, wd *atlasexec.WorkingDir, data *managedData, vars atlasexec.VarArgs) error {
+func (r *AtlasSchemaReconciler) lint(ctx context.Context, wd *atlasexec.WorkingDir, data *managedData, vars atlasexec.Vars2) ([]dbv1alpha1.LintDiagnostic, error) {
 	cli, err := r.atlasClient(wd.Path(), data.Cloud)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	current, err := cli.SchemaInspect(ctx, &atlasexec.SchemaInspectParams{
 		Env:    data.EnvName,
@@ -45,7 +49,7 @@ func (r *AtlasSchemaReconciler) lint(ctx context.Context, wd *atlasexec.WorkingD
 		Format: "{{ sql . }}",
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	plan, err := cli.SchemaApply(ctx, &atlasexec.SchemaApplyParams{
 		Env:    data.EnvName,
@@ -54,7 +58,7 @@ func (r *AtlasSchemaReconciler) lint(ctx context.Context, wd *atlasexec.WorkingD
 		DryRun: true, // Dry run to get pending changes.
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() {
 		dir := wd.Path(lintDirName)
@@ -68,11 +72,11 @@ func (r *AtlasSchemaReconciler) lint(ctx context.Context, wd *atlasexec.WorkingD
 		"2.sql": strings.Join(plan.Changes.Pending, ";\n"),
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	err = wd.CopyFS(lintDirName, dir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	lint, err := cli.MigrateLint(ctx, &atlasexec.MigrateLintParams{
 		Env:    data.EnvName,
@@ -81,46 +85,158 @@ func (r *AtlasSchemaReconciler) lint(ctx context.Context, wd *atlasexec.WorkingD
 		Latest: 1, // Only lint 2.sql, pending changes.
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return destructive(lint)
+	return lintReport(lint, data.Policy, vars)
+}
+
+// lintAnalyzers lists the Atlas analyzers and the prefix of
+// the diagnostic codes they report. The destructive analyzer
+// must be kept first, as the first run verification relies on it.
+var lintAnalyzers = []struct {
+	name, prefix, desc string
+	check              func(*dbv1alpha1.Lint) *dbv1alpha1.CheckConfig
+}{
+	{"destructive", "DS", "destructive changes", func(l *dbv1alpha1.Lint) *dbv1alpha1.CheckConfig { return l.Destructive }},
+	{"data_depend", "MF", "data-dependent changes", func(l *dbv1alpha1.Lint) *dbv1alpha1.CheckConfig { return l.DataDepend }},
+	{"incompatible", "BC", "backward-incompatible changes", func(l *dbv1alpha1.Lint) *dbv1alpha1.CheckConfig { return l.Incompatible }},
+	{"naming", "NM", "naming violations", func(l *dbv1alpha1.Lint) *dbv1alpha1.CheckConfig {
+		if l.Naming == nil {
+			return nil
+		}
+		return &l.Naming.CheckConfig
+	}},
+	{"concurrent_index", "PG1", "non-concurrent index changes", func(l *dbv1alpha1.Lint) *dbv1alpha1.CheckConfig { return l.ConcurrentIndex }},
 }
 
-func destructive(rep *atlasexec.SummaryReport) error {
-	var checks []sqlcheck.Diagnostic
+// lintReport evaluates the report of `atlas migrate lint` against the policy.
+// Diagnostics are at the error level if their analyzer is configured to error.
+// The level of the destructive analyzer is read from the lint_destructive
+// variable if set, as it overrides the policy (e.g., in the first run or once
+// the contract gate is open). Diagnostics of unknown analyzers are at the error
+// level if Atlas failed their file without any other error diagnostic.
+func lintReport(rep *atlasexec.SummaryReport, p *dbv1alpha1.Policy, vars atlasexec.Vars2) ([]dbv1alpha1.LintDiagnostic, error) {
+	var (
+		diags []dbv1alpha1.LintDiagnostic
+		errs  = &lintErr{}
+	)
 	for _, f := range rep.Files {
+		var (
+			failed  bool
+			unknown []int
+		)
 		for _, r := range f.Reports {
-			if f.Error == "" {
-				continue
-			}
 			for _, diag := range r.Diagnostics {
-				if strings.HasPrefix(diag.Code, "DS") {
-					checks = append(checks, diag)
+				d := dbv1alpha1.LintDiagnostic{Code: diag.Code, Text: diag.Text}
+				switch c := analyzerOf(diag.Code); {
+				case c < 0:
+					unknown = append(unknown, len(diags))
+				case f.Error != "":
+					d.Error = lintError(c, p, vars)
+					failed = failed || d.Error
 				}
+				diags = append(diags, d)
+			}
+		}
+		if f.Error != "" && !failed {
+			for _, i := range unknown {
+				diags[i].Error = true
 			}
 		}
 	}
-	if len(checks) > 0 {
-		return &destructiveErr{diags: checks}
+	for _, d := range diags {
+		if d.Error {
+			errs.diags = append(errs.diags, d)
+		}
+	}
+	if len(errs.diags) > 0 {
+		return diags, errs
+	}
+	return diags, nil
+}
+
+// lintError reports if the diagnostics of the given analyzer are at the error level.
+func lintError(c int, p *dbv1alpha1.Policy, vars atlasexec.Vars2) bool {
+	if v, ok := vars["lint_destructive"]; ok && c == 0 {
+		return fmt.Sprint(v) == "true"
+	}
+	if p == nil || p.Lint == nil {
+		return false
+	}
+	cfg := lintAnalyzers[c].check(p.Lint)
+	return cfg != nil && cfg.Error
+}
+
+// analyzerOf returns the index of the analyzer in lintAnalyzers
+// that reported the given code, or -1 if it is unknown.
+func analyzerOf(code string) int {
+	for i, a := range lintAnalyzers {
+		if strings.HasPrefix(code, a.prefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+// destructive returns the diagnostics reported by the destructive analyzer.
+func destructive(diags []dbv1alpha1.LintDiagnostic) []dbv1alpha1.LintDiagnostic {
+	var ds []dbv1alpha1.LintDiagnostic
+	for _, d := range diags {
+		if analyzerOf(d.Code) == 0 {
+			ds = append(ds, d)
+		}
 	}
-	return nil
+	return ds
 }
 
-type destructiveErr struct {
-	diags []sqlcheck.Diagnostic
+// lintErr is returned when the linting policy rejects the pending changes.
+type lintErr struct {
+	diags []dbv1alpha1.LintDiagnostic
 }
 
-func (d *destructiveErr) Error() string {
-	var buf strings.Builder
-	buf.WriteString("destructive changes detected:\n")
-	for _, diag := range d.diags {
-		buf.WriteString("- " + diag.Text + "\n")
+func (e *lintErr) Error() string {
+	var (
+		buf   strings.Builder
+		descs []string
+		byDsc = make(map[string][]dbv1alpha1.LintDiagnostic)
+	)
+	for _, d := range e.diags {
+		desc := "lint issues"
+		if i := analyzerOf(d.Code); i >= 0 {
+			desc = lintAnalyzers[i].desc
+		}
+		if _, ok := byDsc[desc]; !ok {
+			descs = append(descs, desc)
+		}
+		byDsc[desc] = append(byDsc[desc], d)
+	}
+	for _, desc := range descs {
+		buf.WriteString(desc + " detected:\n")
+		for _, d := range byDsc[desc] {
+			buf.WriteString("- " + d.Text + "\n")
+		}
 	}
 	return buf.String()
 }
 
-func (d *destructiveErr) FirstRun() (string, string) {
-	return "FirstRunDestructive", d.Error() + "\n" +
+// firstRunErr returns the reason and message reported when
+// the first run of a schema contains destructive changes.
+func firstRunErr(ds []dbv1alpha1.LintDiagnostic) (string, string) {
+	return "FirstRunDestructive", (&lintErr{diags: ds}).Error() + "\n" +
 		"To prevent accidental drop of resources, first run of a schema must not contain destructive changes.\n" +
+		fmt.Sprintf("To apply these changes anyway, annotate the resource with %s=%s\n", dbv1alpha1.AnnotationAckDestructive, destructiveHash(ds)) +
 		"Read more: https://atlasgo.io/integrations/kubernetes/#destructive-changes"
 }
+
+// destructiveHash returns a short hash identifying the given destructive changes.
+// It is used to acknowledge the exact changes reported on the first run.
+func destructiveHash(ds []dbv1alpha1.LintDiagnostic) string {
+	h := sha256.New()
+	for _, d := range ds {
+		h.Write([]byte(d.Code))
+		h.Write([]byte{0})
+		h.Write([]byte(d.Text))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
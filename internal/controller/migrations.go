@@ -0,0 +1,133 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+// outputMigrationsDir is the directory in the working directory
+// the generated migration directory is written to.
+const outputMigrationsDir = "output-migrations"
+
+// writeMigrations generates a versioned migration file for the changes of the desired
+// schema, if any. Failures are reported as events, and do not fail the reconciliation.
+func (r *AtlasSchemaReconciler) writeMigrations(ctx context.Context, res *dbv1alpha1.AtlasSchema, cli AtlasExec, wd *atlasexec.WorkingDir, data *managedData) {
+	if o := res.Spec.Output; o == nil || o.Migrations == nil {
+		return
+	}
+	switch name, err := r.migrationsConfigMap(ctx, res, cli, wd, data); {
+	case err != nil:
+		log.FromContext(ctx).Error(err, "failed to generate the migration files")
+		r.recorder.Event(res, corev1.EventTypeWarning, "GeneratingMigrations", err.Error())
+	case name != "":
+		r.recorder.Eventf(res, corev1.EventTypeNormal, "GeneratedMigration", "Generated migration file %q in ConfigMap %q", name, res.MigrationsConfigMapName())
+	}
+}
+
+// migrationsConfigMap replays the migration directory stored in the ConfigMap and diffs it
+// against the desired schema. The generated file and the updated atlas.sum are written back
+// to the ConfigMap. It returns the name of the generated file, or empty if nothing changed.
+func (r *AtlasSchemaReconciler) migrationsConfigMap(ctx context.Context, res *dbv1alpha1.AtlasSchema, cli AtlasExec, wd *atlasexec.WorkingDir, data *managedData) (string, error) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      res.MigrationsConfigMapName(),
+			Namespace: res.Namespace,
+		},
+	}
+	switch err := r.Get(ctx, client.ObjectKeyFromObject(cm), cm); {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return "", err
+	// ConfigMaps created by previous versions are owned by the resource.
+	case cm.Labels[dbv1alpha1.LabelMigrationsOf] != res.Name && !metav1.IsControlledBy(cm, res):
+		return "", fmt.Errorf("ConfigMap %q was not created by this resource, set `output.migrations.configMapName` to another name", cm.Name)
+	}
+	dir := wd.Path(outputMigrationsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	for name, content := range cm.Data {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			return "", err
+		}
+	}
+	err := cli.MigrateDiff(ctx, &MigrateDiffParams{
+		Env:    data.EnvName,
+		DirURL: "file://" + dir,
+		To:     data.Desired.String(),
+		Name:   res.Spec.Output.Migrations.Name,
+	})
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var (
+		added string
+		files = make(map[string]string, len(entries))
+	)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return "", err
+		}
+		files[e.Name()] = string(b)
+		if _, ok := cm.Data[e.Name()]; !ok && e.Name() != "atlas.sum" {
+			added = e.Name()
+		}
+	}
+	// The migration directory is in sync with the desired schema.
+	if added == "" && maps.Equal(files, cm.Data) {
+		return "", nil
+	}
+	// The directory is not owned by the resource, as it is an audit trail
+	// that outlives the resource, and may be consumed by an AtlasMigration.
+	_, err = controllerutil.CreateOrUpdate(ctx, r, cm, func() error {
+		if cm.Labels == nil {
+			cm.Labels = make(map[string]string, 1)
+		}
+		cm.Labels[dbv1alpha1.LabelMigrationsOf] = res.Name
+		cm.OwnerReferences = slices.DeleteFunc(cm.OwnerReferences, func(o metav1.OwnerReference) bool {
+			return o.UID == res.UID
+		})
+		cm.Data = files
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return added, nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+func TestReconcile_OutputMigrations(t *testing.T) {
+	meta := objmeta()
+	obj := &dbv1alpha1.AtlasSchema{
+		ObjectMeta: meta,
+		Spec: dbv1alpha1.AtlasSchemaSpec{
+			TargetSpec: dbv1alpha1.TargetSpec{URL: "sqlite://file2/?mode=memory"},
+			Schema:     dbv1alpha1.Schema{SQL: "CREATE TABLE foo(id INT PRIMARY KEY);"},
+			DevURL:     "sqlite://dev/?mode=memory",
+			Output: &dbv1alpha1.Output{
+				Migrations: &dbv1alpha1.MigrationsOutput{Name: "schema"},
+			},
+		},
+		Status: dbv1alpha1.AtlasSchemaStatus{
+			// Not the first run of the resource.
+			LastApplied: 1,
+			Conditions: []metav1.Condition{
+				{Type: "Ready", Status: metav1.ConditionFalse},
+			},
+		},
+	}
+	mockExec := &mockAtlasExec{}
+	mockExec.whoami.err = atlasexec.ErrRequireLogin
+	mockExec.schemaInspect.res = ptr.To("")
+	mockExec.lint.res = &atlasexec.SummaryReport{}
+	mockExec.schemaApply.res = &atlasexec.SchemaApply{}
+	// Each call generates a file, until the directory is in sync.
+	var stmts []string
+	mockExec.diffFn = func(p *MigrateDiffParams) error {
+		dir := strings.TrimPrefix(p.DirURL, "file://")
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		var sum []string
+		for _, e := range entries {
+			if e.Name() != "atlas.sum" {
+				sum = append(sum, e.Name())
+			}
+		}
+		if len(stmts) == 0 {
+			return nil
+		}
+		name := fmt.Sprintf("%d_%s.sql", len(sum)+1, p.Name)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(strings.Join(stmts, ";\n")), 0o644); err != nil {
+			return err
+		}
+		stmts = nil
+		return os.WriteFile(filepath.Join(dir, "atlas.sum"), []byte(strings.Join(append(sum, name), "\n")), 0o644)
+	}
+	h, reconcile := newRunner(NewAtlasSchemaReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(obj)
+		cb.WithObjects(obj)
+	}, mockExec)
+
+	stmts = []string{"CREATE TABLE `foo` (`id` int NOT NULL, PRIMARY KEY (`id`))"}
+	reconcile(obj, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+	})
+	require.Equal(t, "file://schema.sql", mockExec.migrateDiff.res.To)
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: obj.Name + "-migrations", Namespace: meta.Namespace}}
+	h.get(t, cm)
+	require.Equal(t, map[string]string{
+		"1_schema.sql": "CREATE TABLE `foo` (`id` int NOT NULL, PRIMARY KEY (`id`))",
+		"atlas.sum":    "1_schema.sql",
+	}, cm.Data)
+	// The directory outlives the resource.
+	require.Empty(t, cm.OwnerReferences)
+	require.Equal(t, obj.Name, cm.Labels[dbv1alpha1.LabelMigrationsOf])
+	require.Equal(t, []string{
+		"Normal Applied Applied schema",
+		`Normal GeneratedMigration Generated migration file "1_schema.sql" in ConfigMap "` + cm.Name + `"`,
+	}, h.events())
+
+	// The stored directory is replayed, and only the new changes are generated.
+	stmts = []string{"ALTER TABLE `foo` ADD COLUMN `name` text NULL"}
+	reconcile(obj, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+	})
+	h.get(t, cm)
+	require.Len(t, cm.Data, 3)
+	require.Equal(t, "ALTER TABLE `foo` ADD COLUMN `name` text NULL", cm.Data["2_schema.sql"])
+	require.Equal(t, "1_schema.sql\n2_schema.sql", cm.Data["atlas.sum"])
+	h.events()
+
+	// Nothing is generated when the directory is in sync.
+	reconcile(obj, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+	})
+	h.get(t, cm)
+	require.Len(t, cm.Data, 3)
+	require.Equal(t, []string{"Normal Applied Applied schema"}, h.events())
+
+	// ConfigMaps created by others are not written.
+	delete(cm.Labels, dbv1alpha1.LabelMigrationsOf)
+	require.NoError(t, h.client.Update(context.Background(), cm))
+	stmts = []string{"DROP TABLE `foo`"}
+	reconcile(obj, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+	})
+	h.get(t, cm)
+	require.Len(t, cm.Data, 3)
+	require.Equal(t, []string{
+		"Normal Applied Applied schema",
+		`Warning GeneratingMigrations ConfigMap "` + cm.Name + "\" was not created by this resource, set `output.migrations.configMapName` to another name",
+	}, h.events())
+}
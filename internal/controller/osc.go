@@ -0,0 +1,299 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+type (
+	// onlineChange is a planned ALTER TABLE statement that
+	// qualifies for an online schema change.
+	onlineChange struct {
+		stmt  string
+		table tableName
+		// alter is the statement without its ALTER TABLE prefix.
+		alter string
+	}
+	// oscPendingErr is returned while an online schema change is running.
+	oscPendingErr struct {
+		job, table string
+	}
+	// oscFailedErr is returned when an online schema change did not complete.
+	oscFailedErr struct {
+		job, table, msg string
+	}
+)
+
+func (e *oscPendingErr) Error() string {
+	return fmt.Sprintf("the online schema change of table %s is running in job %q", e.table, e.job)
+}
+
+// Reason implements the interface used for reporting events.
+func (e *oscPendingErr) Reason() string {
+	return "OnlineSchemaChange"
+}
+
+func (e *oscFailedErr) Error() string {
+	return fmt.Sprintf("the online schema change of table %s in job %q %s, delete the job to retry", e.table, e.job, e.msg)
+}
+
+// Reason implements the interface used for reporting events.
+func (e *oscFailedErr) Reason() string {
+	return "OnlineSchemaChangeFailed"
+}
+
+// alterStmt matches the ALTER TABLE statements, and captures
+// the name of the table and the rest of the statement.
+var alterStmt = regexp.MustCompile("(?is)^\\s*ALTER\\s+TABLE\\s+((?:`?[\\w$]+`?\\.)?`?[\\w$]+`?)\\s+(.+?)\\s*;?\\s*$")
+
+// onlineSchemaChange plans the online schema changes of the target database. It returns
+// the Job to create once the other planned changes are applied, an oscPendingErr if a
+// Job is still running, and an oscFailedErr if a Job failed. The tables altered online
+// are excluded from the atlas.hcl, so that their changes are not applied directly.
+func (r *AtlasSchemaReconciler) onlineSchemaChange(ctx context.Context, cli AtlasExec, wd *atlasexec.WorkingDir, data *managedData, res *dbv1alpha1.AtlasSchema) (*batchv1.Job, *dbv1alpha1.OnlineSchemaChangeStatus, error) {
+	changes, err := r.onlineChanges(ctx, cli, data)
+	if err != nil || len(changes) == 0 {
+		return nil, nil, err
+	}
+	// The online schema changes are run one at a time.
+	c := changes[0]
+	s := &dbv1alpha1.OnlineSchemaChangeStatus{
+		Job:       oscJobName(res, c.stmt),
+		Table:     c.table.Schema + "." + c.table.Name,
+		Statement: c.stmt,
+	}
+	for _, p := range changes[1:] {
+		s.Pending = append(s.Pending, p.stmt)
+	}
+	s.Pending = truncateSQL(s.Pending, sqlLimitSize)
+	job := &batchv1.Job{}
+	switch err := r.Get(ctx, types.NamespacedName{Name: s.Job, Namespace: res.Namespace}, job); {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return nil, s, transient(err)
+	case jobFinished(job, batchv1.JobFailed):
+		return nil, s, &oscFailedErr{job: s.Job, table: s.Table, msg: "failed"}
+	case jobFinished(job, batchv1.JobComplete):
+		// The tool completed, but the statement is still planned.
+		return nil, s, &oscFailedErr{job: s.Job, table: s.Table, msg: "completed, but the statement is still planned"}
+	default:
+		return nil, s, &oscPendingErr{job: s.Job, table: s.Table}
+	}
+	for _, c := range changes {
+		name := c.table.Name
+		if !dbv1alpha1.DriverBySchema(data.URL.Scheme).SchemaBound(*data.URL) {
+			name = c.table.Schema + "." + name
+		}
+		if !slices.Contains(data.Exclude, name) {
+			data.Exclude = append(slices.Clone(data.Exclude), name)
+		}
+	}
+	if err = atlasexec.WithAtlasHCL(data.render)(wd); err != nil {
+		return nil, nil, err
+	}
+	return oscJob(res, data.URL, c, s.Job), s, nil
+}
+
+// onlineChanges returns the planned ALTER TABLE statements that qualify for online schema changes.
+func (r *AtlasSchemaReconciler) onlineChanges(ctx context.Context, cli AtlasExec, data *managedData) ([]onlineChange, error) {
+	o := data.osc
+	plan, err := r.plan(ctx, cli, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	var changes []onlineChange
+	for _, stmt := range plan {
+		m := alterStmt.FindStringSubmatch(stmt)
+		if m == nil {
+			continue
+		}
+		t := touchedTables([]string{stmt})[0]
+		if t.Schema == "" {
+			t.Schema = strings.TrimPrefix(data.URL.Path, "/")
+		}
+		if len(o.Tables) > 0 && !slices.ContainsFunc(o.Tables, func(p string) bool {
+			return globCovers(p, t.Schema+"."+t.Name)
+		}) {
+			continue
+		}
+		changes = append(changes, onlineChange{stmt: stmt, table: t, alter: m[2]})
+	}
+	if len(changes) == 0 {
+		return changes, nil
+	}
+	tables := make([]tableName, len(changes))
+	for i, c := range changes {
+		tables[i] = c.table
+	}
+	stats, err := r.tableStats(ctx, data.URL, tables)
+	if err != nil {
+		return nil, err
+	}
+	// Tables that are not found are created by the planned changes, and
+	// are altered directly, as the tools require an existing table.
+	return slices.DeleteFunc(changes, func(c onlineChange) bool {
+		i := slices.IndexFunc(stats, func(s dbv1alpha1.TableImpact) bool {
+			return s.Table == c.table.Schema+"."+c.table.Name
+		})
+		return i == -1 || (o.MinRows > 0 && stats[i].Rows < o.MinRows)
+	}), nil
+}
+
+// runOnlineSchemaChange creates the Job running the online schema change,
+// and the Secret holding the password of the target database.
+func (r *AtlasSchemaReconciler) runOnlineSchemaChange(ctx context.Context, res *dbv1alpha1.AtlasSchema, data *managedData, job *batchv1.Job) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      oscSecretName(res),
+			Namespace: res.Namespace,
+		},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r, secret, func() error {
+		pass, _ := data.URL.User.Password()
+		secret.Data = map[string][]byte{"password": []byte(pass)}
+		return controllerutil.SetControllerReference(res, secret, r.scheme)
+	}); err != nil {
+		return err
+	}
+	if err := controllerutil.SetControllerReference(res, job, r.scheme); err != nil {
+		return err
+	}
+	return r.Create(ctx, job)
+}
+
+// oscJob returns the Job running the given online schema change.
+func oscJob(res *dbv1alpha1.AtlasSchema, u *url.URL, c onlineChange, name string) *batchv1.Job {
+	var (
+		o    = res.Spec.OnlineSchemaChange
+		port = u.Port()
+		args []string
+		cmd  []string
+	)
+	if port == "" {
+		port = "3306"
+	}
+	conn := []string{
+		"--host=" + u.Hostname(),
+		"--port=" + port,
+		"--user=" + u.User.Username(),
+		// Expanded by Kubernetes from the environment of the container.
+		"--password=$(OSC_PASSWORD)",
+	}
+	switch o.Tool {
+	case dbv1alpha1.OnlineSchemaChangePtOSC:
+		cmd = []string{"pt-online-schema-change"}
+		args = append(conn, "--alter="+c.alter, "--execute")
+		args = append(args, o.Args...)
+		args = append(args, fmt.Sprintf("D=%s,t=%s", c.table.Schema, c.table.Name))
+	default:
+		cmd = []string{"gh-ost"}
+		args = append(conn, "--database="+c.table.Schema, "--table="+c.table.Name, "--alter="+c.alter, "--execute")
+		args = append(args, o.Args...)
+	}
+	labels := map[string]string{
+		labelInstance:                  res.Name,
+		"app.kubernetes.io/name":       "atlas-online-schema-change",
+		"app.kubernetes.io/part-of":    "atlas-operator",
+		"app.kubernetes.io/created-by": "controller-manager",
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: res.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			// Online schema changes are not retried automatically,
+			// a failed change may need to be cleaned up first.
+			BackoffLimit: ptr.To[int32](0),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    string(o.Tool),
+						Image:   o.Image,
+						Command: cmd,
+						Args:    args,
+						Env: []corev1.EnvVar{{
+							Name: "OSC_PASSWORD",
+							ValueFrom: &corev1.EnvVarSource{
+								SecretKeyRef: &corev1.SecretKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{Name: oscSecretName(res)},
+									Key:                  "password",
+								},
+							},
+						}},
+						SecurityContext: &corev1.SecurityContext{
+							RunAsNonRoot:             ptr.To(true),
+							AllowPrivilegeEscalation: ptr.To(false),
+							Capabilities: &corev1.Capabilities{
+								Drop: []corev1.Capability{"ALL"},
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// oscJobName returns the name of the Job running the given statement. The name is set
+// as the "job-name" label of its pods, so long resource names are truncated to fit the
+// label value limit, and suffixed with their hash to keep them distinct.
+func oscJobName(res *dbv1alpha1.AtlasSchema, stmt string) string {
+	var (
+		sum    = sha256.Sum256([]byte(stmt))
+		suffix = "-osc-" + hex.EncodeToString(sum[:])[:8]
+		prefix = res.Name
+	)
+	if len(prefix)+len(suffix) > validation.LabelValueMaxLength {
+		h := sha256.Sum256([]byte(res.Name))
+		prefix = strings.TrimRight(prefix[:validation.LabelValueMaxLength-len(suffix)-9], "-.") + "-" + hex.EncodeToString(h[:])[:8]
+	}
+	return prefix + suffix
+}
+
+// oscSecretName returns the name of the Secret holding the password of the target database.
+func oscSecretName(res *dbv1alpha1.AtlasSchema) string {
+	return res.Name + "-osc"
+}
+
+// jobFinished reports if the Job has the given finished condition.
+func jobFinished(job *batchv1.Job, t batchv1.JobConditionType) bool {
+	return slices.ContainsFunc(job.Status.Conditions, func(c batchv1.JobCondition) bool {
+		return c.Type == t && c.Status == corev1.ConditionTrue
+	})
+}
@@ -0,0 +1,296 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+type (
+	// ownership is the registry of the database objects owned by the AtlasSchema
+	// resources, keyed by target database. It detects resources that manage
+	// overlapping objects of the same database.
+	ownership struct {
+		mu     sync.Mutex
+		claims map[types.NamespacedName]*claim
+		// synced reports if the claims of the existing resources were loaded.
+		synced bool
+		// events, if set, receives the resources whose
+		// conflicts were changed by another resource.
+		events chan event.GenericEvent
+	}
+	// claim describes the objects of a database owned by a resource.
+	claim struct {
+		db      string
+		schemas []string
+		include []string
+		exclude []string
+	}
+	// conflictErr is returned when a resource claims
+	// objects that are owned by other resources.
+	conflictErr struct {
+		conflicts []string
+	}
+)
+
+func (e *conflictErr) Error() string {
+	var buf strings.Builder
+	buf.WriteString("the resource owns objects that are also owned by other resources:\n")
+	for _, c := range e.conflicts {
+		buf.WriteString("- " + c + "\n")
+	}
+	return buf.String()
+}
+
+// Reason implements the interface used for reporting events.
+func (e *conflictErr) Reason() string {
+	return "Conflict"
+}
+
+// newOwnership returns an empty ownership registry.
+func newOwnership() *ownership {
+	return &ownership{claims: make(map[types.NamespacedName]*claim)}
+}
+
+// claim records the objects owned by the given resource, replacing its previous
+// claim. It returns a conflictErr if other resources own some of these objects.
+// The claim is recorded even if it conflicts, so that all the overlapping
+// resources are refused until the conflict is resolved.
+func (o *ownership) claim(name types.NamespacedName, c *claim) error {
+	o.mu.Lock()
+	prev := o.claims[name]
+	o.claims[name] = c
+	var conflicts []string
+	notify := o.changed(name, prev, c)
+	others := o.others(name)
+	slices.SortFunc(others, compareNames)
+	for _, other := range others {
+		if overlap := c.overlaps(o.claims[other]); len(overlap) > 0 {
+			conflicts = append(conflicts, fmt.Sprintf("%s (%s)", other, strings.Join(overlap, ", ")))
+		}
+	}
+	o.mu.Unlock()
+	o.notify(notify)
+	if len(conflicts) > 0 {
+		return &conflictErr{conflicts: conflicts}
+	}
+	return nil
+}
+
+// sync loads the claims of the existing resources into the registry, once. A new
+// registry (e.g., after a restart or a leader change) is empty, and a resource must
+// not be applied before the claims of all the other resources are known.
+func (o *ownership) sync(load func() (map[types.NamespacedName]*claim, error)) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.synced {
+		return nil
+	}
+	claims, err := load()
+	if err != nil {
+		return err
+	}
+	for name, c := range claims {
+		// Claims recorded since the registry was created are up-to-date.
+		if _, ok := o.claims[name]; !ok {
+			o.claims[name] = c
+		}
+	}
+	o.synced = true
+	return nil
+}
+
+// release removes the claim of the given resource, if any.
+func (o *ownership) release(name types.NamespacedName) {
+	o.mu.Lock()
+	prev, ok := o.claims[name]
+	delete(o.claims, name)
+	var notify []types.NamespacedName
+	if ok {
+		notify = o.changed(name, prev, nil)
+	}
+	o.mu.Unlock()
+	o.notify(notify)
+}
+
+// changed returns the resources whose overlap with the given resource
+// differs between its previous and current claims. Must be called with
+// the lock held.
+func (o *ownership) changed(name types.NamespacedName, prev, cur *claim) []types.NamespacedName {
+	var names []types.NamespacedName
+	for _, other := range o.others(name) {
+		c := o.claims[other]
+		if (len(prev.overlaps(c)) > 0) != (len(cur.overlaps(c)) > 0) {
+			names = append(names, other)
+		}
+	}
+	return names
+}
+
+// others returns the resources other than the given one that hold a claim.
+// Must be called with the lock held.
+func (o *ownership) others(name types.NamespacedName) []types.NamespacedName {
+	names := make([]types.NamespacedName, 0, len(o.claims))
+	for n := range o.claims {
+		if n != name {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// notify triggers the reconciliation of the given resources.
+func (o *ownership) notify(names []types.NamespacedName) {
+	if o.events == nil {
+		return
+	}
+	for _, n := range names {
+		o.events <- event.GenericEvent{
+			Object: &dbv1alpha1.AtlasSchema{
+				ObjectMeta: metav1.ObjectMeta{Name: n.Name, Namespace: n.Namespace},
+			},
+		}
+	}
+}
+
+// overlaps returns the patterns of objects owned by both claims.
+func (c *claim) overlaps(o *claim) []string {
+	if c == nil || o == nil || c.db != o.db {
+		return nil
+	}
+	// Claims limited to distinct schemas never overlap.
+	if len(c.schemas) > 0 && len(o.schemas) > 0 && !slices.ContainsFunc(c.schemas, func(s string) bool {
+		return slices.Contains(o.schemas, s)
+	}) {
+		return nil
+	}
+	var overlap []string
+	for _, a := range c.owned() {
+		for _, b := range o.owned() {
+			if !globsIntersect(a, b) || c.excluded(a, b) || o.excluded(a, b) {
+				continue
+			}
+			switch {
+			case globCovers(a, b):
+				overlap = append(overlap, b)
+			case globCovers(b, a):
+				overlap = append(overlap, a)
+			default:
+				overlap = append(overlap, a+" & "+b)
+			}
+		}
+	}
+	return overlap
+}
+
+// owned returns the patterns of the objects owned by the claim.
+// A claim without include patterns owns all the objects.
+func (c *claim) owned() []string {
+	if len(c.include) == 0 {
+		return []string{"*"}
+	}
+	return c.include
+}
+
+// excluded reports if the intersection of the two
+// patterns is excluded from the claim.
+func (c *claim) excluded(a, b string) bool {
+	return slices.ContainsFunc(c.exclude, func(e string) bool {
+		return globCovers(e, a) || globCovers(e, b)
+	})
+}
+
+// databaseKey returns the key of the target database in the ownership registry.
+// Credentials and connection parameters are ignored, except the search_path
+// that limits a PostgreSQL connection to a schema.
+func databaseKey(u *url.URL) string {
+	scheme := u.Scheme
+	switch scheme {
+	case "postgresql":
+		scheme = "postgres"
+	case "maria", "mariadb":
+		scheme = "mysql"
+	}
+	k := scheme + "://" + u.Host + u.Path
+	if sp := u.Query().Get("search_path"); sp != "" {
+		k += "?search_path=" + sp
+	}
+	return k
+}
+
+// globsIntersect reports if some name matches both glob patterns.
+// A '*' matches any sequence of characters, and a '?' matches any single one.
+func globsIntersect(a, b string) bool {
+	memo := make(map[[2]int]bool)
+	var match func(i, j int) bool
+	match = func(i, j int) (ok bool) {
+		k := [2]int{i, j}
+		if v, seen := memo[k]; seen {
+			return v
+		}
+		defer func() { memo[k] = ok }()
+		switch {
+		case i == len(a) && j == len(b):
+			return true
+		case i < len(a) && a[i] == '*':
+			return match(i+1, j) || j < len(b) && match(i, j+1)
+		case j < len(b) && b[j] == '*':
+			return match(i, j+1) || i < len(a) && match(i+1, j)
+		case i < len(a) && j < len(b) && (a[i] == '?' || b[j] == '?' || a[i] == b[j]):
+			return match(i+1, j+1)
+		}
+		return false
+	}
+	return match(0, 0)
+}
+
+// globCovers reports if every name matching the pattern p also matches e.
+func globCovers(e, p string) bool {
+	memo := make(map[[2]int]bool)
+	var match func(i, j int) bool
+	match = func(i, j int) (ok bool) {
+		k := [2]int{i, j}
+		if v, seen := memo[k]; seen {
+			return v
+		}
+		defer func() { memo[k] = ok }()
+		switch {
+		case i == len(e):
+			return j == len(p)
+		case e[i] == '*':
+			return match(i+1, j) || j < len(p) && match(i, j+1)
+		case j == len(p) || p[j] == '*':
+			return false
+		case e[i] == '?' || e[i] == p[j]:
+			return match(i+1, j+1)
+		}
+		return false
+	}
+	return match(0, 0)
+}
+
+func compareNames(a, b types.NamespacedName) int {
+	return strings.Compare(a.String(), b.String())
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+type (
+	// skipAttr is an attribute of the diff.skip block of the atlas.hcl.
+	skipAttr struct {
+		Name, Value string
+	}
+	// contractPendingErr is returned when the contracting changes
+	// of an expand/contract rollout wait for the gate to open.
+	contractPendingErr struct {
+		waiting []string
+		after   time.Duration
+	}
+)
+
+func (e *contractPendingErr) Error() string {
+	var buf strings.Builder
+	buf.WriteString("the contracting changes are held back until:\n")
+	for _, w := range e.waiting {
+		buf.WriteString("- " + w + "\n")
+	}
+	return buf.String()
+}
+
+// Reason implements the interface used for reporting events.
+func (e *contractPendingErr) Reason() string {
+	return "ContractPending"
+}
+
+// skipAttrs lists the attributes of the diff.skip block in order.
+// Drop changes are the contracting changes of expand/contract rollouts.
+var skipAttrs = []struct {
+	name string
+	drop bool
+	get  func(*dbv1alpha1.SkipChanges) bool
+}{
+	{"add_schema", false, func(s *dbv1alpha1.SkipChanges) bool { return s.AddSchema }},
+	{"drop_schema", true, func(s *dbv1alpha1.SkipChanges) bool { return s.DropSchema }},
+	{"modify_schema", false, func(s *dbv1alpha1.SkipChanges) bool { return s.ModifySchema }},
+	{"add_table", false, func(s *dbv1alpha1.SkipChanges) bool { return s.AddTable }},
+	{"drop_table", true, func(s *dbv1alpha1.SkipChanges) bool { return s.DropTable }},
+	{"modify_table", false, func(s *dbv1alpha1.SkipChanges) bool { return s.ModifyTable }},
+	{"add_column", false, func(s *dbv1alpha1.SkipChanges) bool { return s.AddColumn }},
+	{"drop_column", true, func(s *dbv1alpha1.SkipChanges) bool { return s.DropColumn }},
+	{"modify_column", false, func(s *dbv1alpha1.SkipChanges) bool { return s.ModifyColumn }},
+	{"add_index", false, func(s *dbv1alpha1.SkipChanges) bool { return s.AddIndex }},
+	{"drop_index", true, func(s *dbv1alpha1.SkipChanges) bool { return s.DropIndex }},
+	{"modify_index", false, func(s *dbv1alpha1.SkipChanges) bool { return s.ModifyIndex }},
+	{"add_foreign_key", false, func(s *dbv1alpha1.SkipChanges) bool { return s.AddForeignKey }},
+	{"drop_foreign_key", true, func(s *dbv1alpha1.SkipChanges) bool { return s.DropForeignKey }},
+	{"modify_foreign_key", false, func(s *dbv1alpha1.SkipChanges) bool { return s.ModifyForeignKey }},
+}
+
+// DiffConcurrentIndex returns the concurrent_index policy, used by the atlas.hcl template.
+func (d *managedData) DiffConcurrentIndex() *dbv1alpha1.ConcurrentIndex {
+	if p := d.Policy; p != nil && p.Diff != nil {
+		return p.Diff.ConcurrentIndex
+	}
+	return nil
+}
+
+// DiffSkip returns the attributes of the diff.skip block, used by the atlas.hcl template.
+// In expand/contract rollouts, the drop changes are skipped while expanding.
+func (d *managedData) DiffSkip() []skipAttr {
+	var (
+		attrs []skipAttr
+		skip  = &dbv1alpha1.SkipChanges{}
+	)
+	if p := d.Policy; p != nil && p.Diff != nil && p.Diff.Skip != nil {
+		skip = p.Diff.Skip
+	}
+	for _, a := range skipAttrs {
+		switch {
+		case a.get(skip):
+			attrs = append(attrs, skipAttr{Name: a.name, Value: "true"})
+		case a.drop && d.ExpandContract:
+			attrs = append(attrs, skipAttr{Name: a.name, Value: "var.expand"})
+		}
+	}
+	return attrs
+}
+
+// rollout plans the phase of an expand/contract rollout. It returns nil if the planned
+// changes contain no destructive changes, and a contractPendingErr if the contracting
+// changes wait for the gate to open. In the expand phase, the atlas.hcl is rendered
+// again to skip the contracting changes.
+func (r *AtlasSchemaReconciler) rollout(ctx context.Context, cli AtlasExec, wd *atlasexec.WorkingDir, data *managedData, res *dbv1alpha1.AtlasSchema) (*dbv1alpha1.RolloutStatus, error) {
+	diags, err := r.lint(ctx, wd, data, atlasexec.Vars2{
+		"lint_destructive": "true",
+		"expand":           "false",
+	})
+	// Only destructive changes are considered,
+	// the linting policy is enforced when applying.
+	if l := (*lintErr)(nil); errors.As(err, &l) {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ds := destructive(diags)
+	if len(ds) == 0 {
+		return nil, nil
+	}
+	hash := destructiveHash(ds)
+	// The expand phase was applied, apply the contracting
+	// changes once the gate is open.
+	if s := res.Status.Rollout; s != nil && s.Phase == dbv1alpha1.RolloutPhaseExpanded && s.ContractHash == hash {
+		waiting, after, err := r.contractGate(ctx, res, s)
+		if err != nil {
+			return s, err
+		}
+		if len(waiting) > 0 {
+			return s, &contractPendingErr{waiting: waiting, after: after}
+		}
+		c := *s
+		c.Phase = dbv1alpha1.RolloutPhaseContracted
+		return &c, nil
+	}
+	full, err := r.plan(ctx, cli, data, atlasexec.Vars2{"expand": "false"})
+	if err != nil {
+		return nil, err
+	}
+	expand, err := r.plan(ctx, cli, data, atlasexec.Vars2{"expand": "true"})
+	if err != nil {
+		return nil, err
+	}
+	var contract []string
+	for _, stmt := range full {
+		if !slices.Contains(expand, stmt) {
+			contract = append(contract, stmt)
+		}
+	}
+	// The destructive changes cannot be held back.
+	if len(contract) == 0 {
+		return nil, nil
+	}
+	s := &dbv1alpha1.RolloutStatus{
+		Phase:        dbv1alpha1.RolloutPhaseExpanded,
+		ContractHash: hash,
+		Contract:     truncateSQL(contract, sqlLimitSize),
+	}
+	if g := res.Spec.Rollout.Contract; g.DeploymentRef != nil {
+		// Wait for a rollout that started after the expand phase.
+		d := &appsv1.Deployment{}
+		switch err := r.Get(ctx, types.NamespacedName{Name: g.DeploymentRef.Name, Namespace: res.Namespace}, d); {
+		case apierrors.IsNotFound(err):
+		case err != nil:
+			return nil, err
+		case deploymentRolledOut(d):
+			s.DeploymentGeneration = d.Generation + 1
+		default:
+			s.DeploymentGeneration = d.Generation
+		}
+	}
+	data.Expand = true
+	if err = atlasexec.WithAtlasHCL(data.render)(wd); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// plan returns the statements planned for the target database.
+func (r *AtlasSchemaReconciler) plan(ctx context.Context, cli AtlasExec, data *managedData, vars atlasexec.VarArgs) ([]string, error) {
+	plan, err := cli.SchemaApply(ctx, &atlasexec.SchemaApplyParams{
+		Env:    data.EnvName,
+		Vars:   vars,
+		To:     data.Desired.String(),
+		DryRun: true, // Dry run to get pending changes.
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan.Changes.Pending, nil
+}
+
+// contractGate returns the conditions of the contract gate that are not met yet,
+// and the duration after which the gate should be checked again, if known.
+func (r *AtlasSchemaReconciler) contractGate(ctx context.Context, res *dbv1alpha1.AtlasSchema, s *dbv1alpha1.RolloutStatus) (waiting []string, after time.Duration, _ error) {
+	g := res.Spec.Rollout.Contract
+	if g.Annotation && res.Annotations[dbv1alpha1.AnnotationApproveContract] != s.ContractHash {
+		waiting = append(waiting, fmt.Sprintf("the resource is annotated with %s=%s", dbv1alpha1.AnnotationApproveContract, s.ContractHash))
+	}
+	if ref := g.DeploymentRef; ref != nil {
+		d := &appsv1.Deployment{}
+		switch err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: res.Namespace}, d); {
+		case err != nil && !apierrors.IsNotFound(err):
+			return nil, 0, transient(err)
+		case err != nil || d.Generation < s.DeploymentGeneration || !deploymentRolledOut(d):
+			waiting = append(waiting, fmt.Sprintf("the deployment %q completes a new rollout", ref.Name))
+		}
+	}
+	if g.Delay != nil {
+		if d := time.Until(s.ExpandedAt.Add(g.Delay.Duration)); d > 0 {
+			waiting = append(waiting, fmt.Sprintf("%s elapsed since the expand phase", g.Delay.Duration))
+			after = d
+		}
+	}
+	return waiting, after, nil
+}
+
+// deploymentRolledOut reports if all the replicas of the Deployment are updated and available.
+func deploymentRolledOut(d *appsv1.Deployment) bool {
+	n, s := ptr.Deref(d.Spec.Replicas, 1), d.Status
+	return s.ObservedGeneration >= d.Generation && s.UpdatedReplicas == n && s.Replicas == n && s.AvailableReplicas == n
+}
+
+// rolledOut updates the status of the rollout after its phase was applied.
+func rolledOut(s *dbv1alpha1.RolloutStatus, report *atlasexec.SchemaApply) {
+	now := metav1.Now()
+	switch s.Phase {
+	case dbv1alpha1.RolloutPhaseExpanded:
+		s.Expand = truncateSQL(report.Changes.Applied, sqlLimitSize)
+		s.ExpandedAt = now
+	case dbv1alpha1.RolloutPhaseContracted:
+		s.ContractedAt = &now
+	}
+}
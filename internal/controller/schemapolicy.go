@@ -0,0 +1,202 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	"github.com/google/cel-go/cel"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+//+kubebuilder:rbac:groups=db.atlasgo.io,resources=atlasschemapolicies,verbs=get;list;watch
+
+type (
+	// policyRule is a compiled rule of an AtlasSchemaPolicy.
+	policyRule struct {
+		name    string // <policy>/<rule>
+		message string
+		prg     cel.Program
+		changes bool // the rule references the planned changes
+	}
+	// policyViolationErr is returned when the desired
+	// schema does not satisfy the matching policies.
+	policyViolationErr struct {
+		violations []string
+	}
+)
+
+func (e *policyViolationErr) Error() string {
+	var buf strings.Builder
+	buf.WriteString("schema policy violations detected:\n")
+	for _, v := range e.violations {
+		buf.WriteString("- " + v + "\n")
+	}
+	return buf.String()
+}
+
+// Reason implements the interface used for reporting events.
+func (e *policyViolationErr) Reason() string {
+	return "PolicyViolation"
+}
+
+// checkPolicies evaluates the rules of the AtlasSchemaPolicy resources matching
+// the given AtlasSchema against its desired schema. It returns a policyViolationErr
+// if any of the rules fails.
+func (r *AtlasSchemaReconciler) checkPolicies(ctx context.Context, cli AtlasExec, data *managedData, res *dbv1alpha1.AtlasSchema) error {
+	rules, err := r.policyRules(ctx, res)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+	vars := map[string]any{"changes": []string{}}
+	ins, err := cli.SchemaInspect(ctx, &atlasexec.SchemaInspectParams{
+		Env:    data.EnvName,
+		URL:    data.Desired.String(),
+		Format: "{{ json . }}",
+	})
+	if err != nil {
+		if !isSQLErr(err) {
+			err = transient(err)
+		}
+		return err
+	}
+	var schema map[string]any
+	if err = json.Unmarshal([]byte(ins), &schema); err != nil {
+		return fmt.Errorf("decoding the desired schema: %w", err)
+	}
+	vars["schema"] = schema
+	for _, rule := range rules {
+		if !rule.changes {
+			continue
+		}
+		// The planned changes are computed only if any of the rules requires them.
+		plan, err := cli.SchemaApply(ctx, &atlasexec.SchemaApplyParams{
+			Env:    data.EnvName,
+			To:     data.Desired.String(),
+			DryRun: true,
+		})
+		if err != nil {
+			if !isSQLErr(err) {
+				err = transient(err)
+			}
+			return err
+		}
+		vars["changes"] = plan.Changes.Pending
+		break
+	}
+	violations := &policyViolationErr{}
+	for _, rule := range rules {
+		out, _, err := rule.prg.Eval(vars)
+		switch {
+		case err != nil:
+			violations.violations = append(violations.violations, fmt.Sprintf("%s: %s (%v)", rule.name, rule.message, err))
+		case out.Value() != true:
+			violations.violations = append(violations.violations, fmt.Sprintf("%s: %s", rule.name, rule.message))
+		}
+	}
+	if len(violations.violations) > 0 {
+		return violations
+	}
+	return nil
+}
+
+// policyRules returns the compiled rules of the policies matching the given resource.
+func (r *AtlasSchemaReconciler) policyRules(ctx context.Context, res *dbv1alpha1.AtlasSchema) ([]policyRule, error) {
+	list := &dbv1alpha1.AtlasSchemaPolicyList{}
+	if err := r.List(ctx, list); err != nil {
+		return nil, transient(err)
+	}
+	var rules []policyRule
+	for _, p := range list.Items {
+		switch ok, err := p.Matches(res); {
+		case err != nil:
+			return nil, fmt.Errorf("policy %q: %w", p.Name, err)
+		case !ok:
+			continue
+		}
+		for _, pr := range p.Spec.Rules {
+			rule, err := compileRule(pr)
+			if err != nil {
+				return nil, fmt.Errorf("policy %q: rule %q: %w", p.Name, pr.Name, err)
+			}
+			rule.name = p.Name + "/" + pr.Name
+			rules = append(rules, *rule)
+		}
+	}
+	return rules, nil
+}
+
+// compileRule compiles the CEL expression of the given rule.
+func compileRule(pr dbv1alpha1.SchemaPolicyRule) (*policyRule, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("schema", cel.DynType),
+		cel.Variable("changes", cel.ListType(cel.StringType)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	ast, iss := env.Compile(pr.Expression)
+	if err := iss.Err(); err != nil {
+		return nil, err
+	}
+	if !ast.OutputType().IsExactType(cel.BoolType) {
+		return nil, fmt.Errorf("expression must evaluate to bool, got %s", ast.OutputType())
+	}
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+	rule := &policyRule{message: pr.Message, prg: prg}
+	if rule.message == "" {
+		rule.message = pr.Expression
+	}
+	for _, ref := range ast.NativeRep().ReferenceMap() {
+		if ref.Name == "changes" {
+			rule.changes = true
+		}
+	}
+	return rule, nil
+}
+
+// mapPolicy returns the requests of the AtlasSchema resources matching the policy.
+func (r *AtlasSchemaReconciler) mapPolicy(ctx context.Context, o client.Object) []reconcile.Request {
+	p, ok := o.(*dbv1alpha1.AtlasSchemaPolicy)
+	if !ok {
+		return nil
+	}
+	list := &dbv1alpha1.AtlasSchemaList{}
+	if err := r.List(ctx, list); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list AtlasSchema resources")
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i := range list.Items {
+		if ok, err := p.Matches(&list.Items[i]); err == nil && ok {
+			reqs = append(reqs, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: list.Items[i].Name, Namespace: list.Items[i].Namespace},
+			})
+		}
+	}
+	return reqs
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+func TestReconcile_SchemaPolicy(t *testing.T) {
+	meta := objmeta()
+	meta.Labels = map[string]string{"team": "billing"}
+	obj := &dbv1alpha1.AtlasSchema{
+		ObjectMeta: meta,
+		Spec: dbv1alpha1.AtlasSchemaSpec{
+			TargetSpec: dbv1alpha1.TargetSpec{URL: "sqlite://file2/?mode=memory"},
+			Schema:     dbv1alpha1.Schema{SQL: "CREATE TABLE foo(id INT, body TEXT);"},
+			DevURL:     "sqlite://dev/?mode=memory",
+		},
+		Status: dbv1alpha1.AtlasSchemaStatus{
+			Conditions: []metav1.Condition{
+				{Type: "Ready", Status: metav1.ConditionFalse},
+			},
+			LastApplied: 1,
+		},
+	}
+	policy := &dbv1alpha1.AtlasSchemaPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "billing"},
+		Spec: dbv1alpha1.AtlasSchemaPolicySpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "billing"}},
+			Rules: []dbv1alpha1.SchemaPolicyRule{
+				{
+					Name:       "primary-key",
+					Expression: "schema.schemas.all(s, s.tables.all(t, has(t.primary_key)))",
+					Message:    "every table must have a primary key",
+				},
+				{
+					Name:       "no-text",
+					Expression: "schema.schemas.all(s, s.tables.all(t, t.columns.all(c, c.type != 'text')))",
+				},
+				{
+					Name:       "no-drop",
+					Expression: "!changes.exists(c, c.startsWith('DROP'))",
+				},
+			},
+		},
+	}
+	mockExec := &mockAtlasExec{}
+	mockExec.whoami.err = atlasexec.ErrRequireLogin
+	mockExec.schemaApply.res = &atlasexec.SchemaApply{
+		Changes: atlasexec.Changes{Pending: []string{"DROP TABLE bar"}},
+	}
+	mockExec.inspectFn = func(p *atlasexec.SchemaInspectParams) (string, error) {
+		require.Equal(t, "{{ json . }}", p.Format)
+		require.Equal(t, "file://schema.sql", p.URL)
+		return `{"schemas":[{"name":"main","tables":[{"name":"foo","columns":[{"name":"id","type":"int"},{"name":"body","type":"text"}]}]}]}`, nil
+	}
+	h, reconcile := newRunner(NewAtlasSchemaReconciler, func(cb *fake.ClientBuilder) {
+		cb.WithStatusSubresource(obj)
+		cb.WithObjects(obj, policy)
+	}, mockExec)
+	reconcile(obj, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+		require.EqualValues(t, ctrl.Result{}, result)
+	})
+	res := &dbv1alpha1.AtlasSchema{ObjectMeta: meta}
+	h.get(t, res)
+	require.False(t, res.IsReady())
+	cond := res.Status.Conditions[0]
+	require.Equal(t, "PolicyViolation", cond.Reason)
+	require.Equal(t, `schema policy violations detected:
+- billing/primary-key: every table must have a primary key
+- billing/no-text: schema.schemas.all(s, s.tables.all(t, t.columns.all(c, c.type != 'text')))
+- billing/no-drop: !changes.exists(c, c.startsWith('DROP'))
+`, cond.Message)
+	// The planned changes were computed, but not applied.
+	require.True(t, mockExec.applyParams.DryRun)
+
+	// Policies do not apply to resources that do not match their selector.
+	policy.Spec.Selector.MatchLabels["team"] = "payments"
+	h.patch(t, policy)
+	reconcile(obj, func(result ctrl.Result, err error) {
+		require.NoError(t, err)
+	})
+	h.get(t, res)
+	require.True(t, res.IsReady())
+}
+
+func TestCompileRule(t *testing.T) {
+	_, err := compileRule(dbv1alpha1.SchemaPolicyRule{Expression: "schema.foo +"})
+	require.Error(t, err)
+	_, err = compileRule(dbv1alpha1.SchemaPolicyRule{Expression: "changes.size()"})
+	require.EqualError(t, err, "expression must evaluate to bool, got int")
+	rule, err := compileRule(dbv1alpha1.SchemaPolicyRule{Expression: "has(schema.schemas)"})
+	require.NoError(t, err)
+	require.False(t, rule.changes)
+	require.Equal(t, "has(schema.schemas)", rule.message)
+	rule, err = compileRule(dbv1alpha1.SchemaPolicyRule{Expression: "changes.size() < 10"})
+	require.NoError(t, err)
+	require.True(t, rule.changes)
+}
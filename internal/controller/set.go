@@ -0,0 +1,295 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"ariga.io/atlas/sql/sqlclient"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+// catalogRowsFn runs a read-only query against the catalog database and
+// returns its rows, keyed by the column names.
+type catalogRowsFn func(context.Context, *url.URL, string) ([]map[string]string, error)
+
+// generateTargets returns the target databases generated for a set, ordered by name.
+func generateTargets(ctx context.Context, r client.Reader, ns string, g dbv1alpha1.TargetGenerator, query catalogRowsFn) ([]dbv1alpha1.GeneratedTarget, error) {
+	var targets []dbv1alpha1.GeneratedTarget
+	switch {
+	case g.Secrets != nil:
+		sel, err := metav1.LabelSelectorAsSelector(&g.Secrets.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secrets selector: %w", err)
+		}
+		list := &corev1.SecretList{}
+		if err := r.List(ctx, list, client.InNamespace(ns), client.MatchingLabelsSelector{Selector: sel}); err != nil {
+			return nil, transient(err)
+		}
+		for _, s := range list.Items {
+			targets = append(targets, dbv1alpha1.GeneratedTarget{
+				Name: s.Name,
+				TargetSpec: dbv1alpha1.TargetSpec{
+					URLFrom: dbv1alpha1.Secret{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: s.Name},
+							Key:                  g.Secrets.SecretKey(),
+						},
+					},
+				},
+			})
+		}
+	case len(g.List) > 0:
+		targets = slices.Clone(g.List)
+	case g.Query != nil:
+		var err error
+		if targets, err = queryTargets(ctx, r, ns, g.Query, query); err != nil {
+			return nil, err
+		}
+	}
+	slices.SortFunc(targets, func(a, b dbv1alpha1.GeneratedTarget) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for i := 1; i < len(targets); i++ {
+		if targets[i].Name == targets[i-1].Name {
+			return nil, fmt.Errorf("duplicate target %q", targets[i].Name)
+		}
+	}
+	return targets, nil
+}
+
+// queryTargets returns a target for every row returned by the query of the generator.
+func queryTargets(ctx context.Context, r client.Reader, ns string, g *dbv1alpha1.QueryGenerator, query catalogRowsFn) ([]dbv1alpha1.GeneratedTarget, error) {
+	nameT, err := template.New("name").Option("missingkey=error").Parse(g.NameTemplate())
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %w", err)
+	}
+	urlT, err := template.New("url").Option("missingkey=error").Parse(g.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url template: %w", err)
+	}
+	u, err := g.Catalog.DatabaseURL(ctx, r, ns)
+	if err != nil {
+		return nil, transient(err)
+	}
+	rows, err := query(ctx, u, g.Query)
+	if err != nil {
+		return nil, transient(fmt.Errorf("querying the catalog database: %w", err))
+	}
+	hosts := g.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{u.Hostname()}
+	}
+	targets := make([]dbv1alpha1.GeneratedTarget, 0, len(rows))
+	for _, row := range rows {
+		name, err := execTemplate(nameT, row)
+		if err != nil {
+			return nil, err
+		}
+		name = strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+		if name == "" {
+			return nil, fmt.Errorf("empty target name for row %v", row)
+		}
+		// Escape the values of the row, so they cannot change
+		// the host or the credentials of the generated URL.
+		escaped := make(map[string]string, len(row))
+		for k, v := range row {
+			escaped[k] = strings.ReplaceAll(url.QueryEscape(v), "+", "%20")
+		}
+		rawURL, err := execTemplate(urlT, escaped)
+		if err != nil {
+			return nil, err
+		}
+		t := dbv1alpha1.GeneratedTarget{Name: name}
+		if t.TargetSpec, err = queryTarget(rawURL, hosts, g.PasswordFrom); err != nil {
+			return nil, fmt.Errorf("target %q: %w", name, err)
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// queryTarget returns the target of a generated URL. If the password is read from a
+// Secret, the URL is set as credentials, to keep the password out of the child resource.
+// URLs pointing to hosts that do not match any of the given patterns are rejected.
+func queryTarget(raw string, hosts []string, passwordFrom dbv1alpha1.Secret) (dbv1alpha1.TargetSpec, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return dbv1alpha1.TargetSpec{}, fmt.Errorf("invalid url: %w", err)
+	}
+	if !slices.ContainsFunc(hosts, func(p string) bool {
+		ok, err := path.Match(p, u.Hostname())
+		return err == nil && ok
+	}) {
+		return dbv1alpha1.TargetSpec{}, fmt.Errorf("host %q is not allowed, expected one of: %s", u.Hostname(), strings.Join(hosts, ", "))
+	}
+	if passwordFrom.SecretKeyRef == nil {
+		return dbv1alpha1.TargetSpec{URL: u.String()}, nil
+	}
+	if _, ok := u.User.Password(); ok {
+		return dbv1alpha1.TargetSpec{}, fmt.Errorf("url must not contain a password when passwordFrom is set")
+	}
+	c := dbv1alpha1.Credentials{
+		Scheme:       u.Scheme,
+		User:         u.User.Username(),
+		PasswordFrom: passwordFrom,
+		Host:         u.Hostname(),
+		Database:     strings.TrimPrefix(u.Path, "/"),
+	}
+	if p := u.Port(); p != "" {
+		if c.Port, err = strconv.Atoi(p); err != nil {
+			return dbv1alpha1.TargetSpec{}, fmt.Errorf("invalid port %q", p)
+		}
+	}
+	if q := u.Query(); len(q) > 0 {
+		c.Parameters = make(map[string]string, len(q))
+		for k := range q {
+			c.Parameters[k] = q.Get(k)
+		}
+	}
+	return dbv1alpha1.TargetSpec{Credentials: c}, nil
+}
+
+// execTemplate executes the template with the columns of a row.
+func execTemplate(t *template.Template, row map[string]string) (string, error) {
+	var b bytes.Buffer
+	if err := t.Execute(&b, row); err != nil {
+		return "", fmt.Errorf("executing %s template: %w", t.Name(), err)
+	}
+	return b.String(), nil
+}
+
+// queryCatalogRows runs the query in a read-only transaction against the catalog
+// database. NULL values are returned as empty strings.
+func queryCatalogRows(ctx context.Context, u *url.URL, query string) ([]map[string]string, error) {
+	c, err := sqlclient.OpenURL(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	tx, err := c.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var result []map[string]string
+	for rows.Next() {
+		vs := make([]sql.NullString, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vs {
+			ptrs[i] = &vs[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(cols))
+		for i, c := range cols {
+			row[c] = vs[i].String
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// setResult returns the result of a successful set reconciliation.
+// Targets generated by a query are generated again after the interval.
+func setResult(g dbv1alpha1.TargetGenerator) ctrl.Result {
+	if g.Query != nil {
+		return ctrl.Result{RequeueAfter: g.Query.QueryInterval()}
+	}
+	return ctrl.Result{}
+}
+
+// childName returns the name of the child resource managing the target of a set.
+func childName(set string, t dbv1alpha1.GeneratedTarget) (string, error) {
+	name := set + "-" + t.Name
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", fmt.Errorf("invalid resource name %q for target %q: %s", name, t.Name, strings.Join(errs, ", "))
+	}
+	return name, nil
+}
+
+// childMeta sets the labels and annotations of the template on the child
+// resource of a set. Labels and annotations set by others are kept.
+func childMeta(set string, tmpl dbv1alpha1.TemplateMeta, obj client.Object) {
+	l, a := obj.GetLabels(), obj.GetAnnotations()
+	if l == nil {
+		l = make(map[string]string, len(tmpl.Labels)+1)
+	}
+	if a == nil && len(tmpl.Annotations) > 0 {
+		a = make(map[string]string, len(tmpl.Annotations))
+	}
+	for k, v := range tmpl.Labels {
+		l[k] = v
+	}
+	for k, v := range tmpl.Annotations {
+		a[k] = v
+	}
+	l[dbv1alpha1.LabelSet] = set
+	obj.SetLabels(l)
+	obj.SetAnnotations(a)
+}
+
+// setChildren returns the selector of the child resources of a set.
+func setChildren(set client.Object) []client.ListOption {
+	return []client.ListOption{
+		client.InNamespace(set.GetNamespace()),
+		client.MatchingLabels{dbv1alpha1.LabelSet: set.GetName()},
+	}
+}
+
+// targetStatus returns the state of a target from the conditions of its child resource.
+func targetStatus(name string, child client.Object, conds []metav1.Condition) dbv1alpha1.SetTargetStatus {
+	s := dbv1alpha1.SetTargetStatus{Name: name, Resource: child.GetName()}
+	if c := meta.FindStatusCondition(conds, "Ready"); c != nil {
+		s.Ready, s.Reason, s.Message = c.Status == metav1.ConditionTrue, c.Reason, c.Message
+	}
+	return s
+}
+
+// matchSecret reports if the secret is selected by the generator.
+func matchSecret(g dbv1alpha1.TargetGenerator, o client.Object) bool {
+	if g.Secrets == nil {
+		return false
+	}
+	sel, err := metav1.LabelSelectorAsSelector(&g.Secrets.Selector)
+	return err == nil && sel.Matches(labels.Set(o.GetLabels()))
+}
@@ -0,0 +1,251 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"ariga.io/atlas/sql/sqlclient"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+// tenantSchemasFn returns the names of the schemas in the target database.
+type tenantSchemasFn func(context.Context, *url.URL) ([]string, error)
+
+// reconcileTenants applies the desired schema to the tenant schemas of the target
+// database, by creating an AtlasSchema bound to the schema of every tenant.
+// The tenants are reconciled independently, a failing tenant does not block the others.
+func (r *AtlasSchemaReconciler) reconcileTenants(ctx context.Context, res *dbv1alpha1.AtlasSchema) (ctrl.Result, error) {
+	schemas, err := discoverTenants(ctx, r, res.Namespace, res.Spec.TargetSpec, res.Spec.Tenants, r.tenantSchemas)
+	if err != nil {
+		res.SetNotReady("DiscoveringTenants", err.Error())
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	items := make([]fanoutItem, 0, len(schemas))
+	for _, s := range schemas {
+		name, err := tenantName(res.Name, s)
+		if err != nil {
+			res.SetNotReady("ReconcilingTenants", err.Error())
+			r.recordErrEvent(res, err)
+			return result(err)
+		}
+		sc := &dbv1alpha1.AtlasSchema{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: res.Namespace},
+		}
+		items = append(items, fanoutItem{
+			name:  s,
+			child: sc,
+			mutate: func() {
+				tenantMeta(res, sc)
+				sc.Spec = *res.Spec.DeepCopy()
+				sc.Spec.Tenants, sc.Spec.Tenant = nil, s
+				tenantConfigMaps(&sc.Spec)
+			},
+			status: func() dbv1alpha1.SetTargetStatus {
+				return targetStatus(s, sc, sc.Status.Conditions)
+			},
+		})
+	}
+	f := &fanout{Client: r, scheme: r.scheme, recorder: r.recorder, kind: "AtlasSchema", noun: "tenant"}
+	statuses, err := f.reconcile(ctx, res, items, &dbv1alpha1.AtlasSchemaList{}, tenantChildren(res)...)
+	if err != nil {
+		res.SetNotReady("ReconcilingTenants", err.Error())
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	res.SetTenants(statuses)
+	return tenantsResult(res.Spec.Tenants), nil
+}
+
+// reconcileTenants applies the migration directory to the tenant schemas of the target
+// database, by creating an AtlasMigration bound to the schema of every tenant. Unless set
+// explicitly, the revisions table of a tenant is stored in the schema of the tenant.
+// The tenants are reconciled independently, a failing tenant does not block the others.
+func (r *AtlasMigrationReconciler) reconcileTenants(ctx context.Context, res *dbv1alpha1.AtlasMigration) (ctrl.Result, error) {
+	schemas, err := discoverTenants(ctx, r, res.Namespace, res.Spec.TargetSpec, res.Spec.Tenants, r.tenantSchemas)
+	if err != nil {
+		res.SetNotReady("DiscoveringTenants", err.Error())
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	items := make([]fanoutItem, 0, len(schemas))
+	for _, s := range schemas {
+		name, err := tenantName(res.Name, s)
+		if err != nil {
+			res.SetNotReady("ReconcilingTenants", err.Error())
+			r.recordErrEvent(res, err)
+			return result(err)
+		}
+		mig := &dbv1alpha1.AtlasMigration{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: res.Namespace},
+		}
+		items = append(items, fanoutItem{
+			name:  s,
+			child: mig,
+			mutate: func() {
+				tenantMeta(res, mig)
+				mig.Spec = *res.Spec.DeepCopy()
+				mig.Spec.Tenants, mig.Spec.Tenant = nil, s
+				if mig.Spec.RevisionsSchema == "" {
+					mig.Spec.RevisionsSchema = s
+				}
+			},
+			status: func() dbv1alpha1.SetTargetStatus {
+				st := targetStatus(s, mig, mig.Status.Conditions)
+				st.Version = mig.Status.LastAppliedVersion
+				return st
+			},
+		})
+	}
+	f := &fanout{Client: r, scheme: r.scheme, recorder: r.recorder, kind: "AtlasMigration", noun: "tenant"}
+	statuses, err := f.reconcile(ctx, res, items, &dbv1alpha1.AtlasMigrationList{}, tenantChildren(res)...)
+	if err != nil {
+		res.SetNotReady("ReconcilingTenants", err.Error())
+		r.recordErrEvent(res, err)
+		return result(err)
+	}
+	res.SetTenants(statuses)
+	return tenantsResult(res.Spec.Tenants), nil
+}
+
+// discoverTenants returns the tenant schemas, ordered by name. Schemas matching
+// a pattern are discovered in the target database.
+func discoverTenants(ctx context.Context, r client.Reader, ns string, target dbv1alpha1.TargetSpec, t *dbv1alpha1.Tenants, query tenantSchemasFn) ([]string, error) {
+	var schemas []string
+	switch {
+	case len(t.Schemas) > 0:
+		schemas = slices.Clone(t.Schemas)
+	case t.Pattern != "":
+		if _, err := path.Match(t.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid tenants pattern %q: %w", t.Pattern, err)
+		}
+		// The discovery connects to the database, not to a tenant.
+		target.Tenant = ""
+		u, err := target.DatabaseURL(ctx, r, ns)
+		if err != nil {
+			return nil, transient(err)
+		}
+		all, err := query(ctx, u)
+		if err != nil {
+			return nil, transient(err)
+		}
+		for _, s := range all {
+			if ok, _ := path.Match(t.Pattern, s); ok {
+				schemas = append(schemas, s)
+			}
+		}
+	}
+	slices.Sort(schemas)
+	return slices.Compact(schemas), nil
+}
+
+// queryTenantSchemas queries the names of the user schemas of the target
+// database. Only MySQL, MariaDB and PostgreSQL are supported.
+func queryTenantSchemas(ctx context.Context, u *url.URL) ([]string, error) {
+	c, err := sqlclient.OpenURL(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	var query string
+	switch c.Name {
+	case "mysql", "mariadb":
+		query = "SELECT SCHEMA_NAME FROM information_schema.SCHEMATA " +
+			"WHERE SCHEMA_NAME NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')"
+	case "postgres":
+		query = "SELECT nspname FROM pg_namespace WHERE nspname NOT LIKE 'pg\\_%' AND nspname <> 'information_schema'"
+	default:
+		return nil, fmt.Errorf("discovering tenant schemas is not supported for driver %q", c.Name)
+	}
+	rows, err := c.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var schemas []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, s)
+	}
+	return schemas, rows.Err()
+}
+
+// invalidNameChars matches the characters that are not allowed in resource names.
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// tenantName returns the name of the resource managing the schema of a tenant.
+func tenantName(parent, schema string) (string, error) {
+	name := parent + "-" + strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(schema), "-"), "-")
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", fmt.Errorf("invalid resource name %q for tenant %q: %s", name, schema, strings.Join(errs, ", "))
+	}
+	return name, nil
+}
+
+// tenantConfigMaps clears the names of the ConfigMaps the outputs are written to,
+// so that each tenant writes to the ConfigMaps named after its own resource.
+func tenantConfigMaps(s *dbv1alpha1.AtlasSchemaSpec) {
+	if s.Inspect != nil {
+		s.Inspect.ConfigMapName = ""
+	}
+	if s.Docs != nil {
+		s.Docs.ConfigMapName = ""
+	}
+	if s.Output != nil && s.Output.Migrations != nil {
+		s.Output.Migrations.ConfigMapName = ""
+	}
+}
+
+// tenantMeta labels the resource of a tenant with the name of its parent.
+func tenantMeta(parent, child client.Object) {
+	l := child.GetLabels()
+	if l == nil {
+		l = make(map[string]string, 1)
+	}
+	l[dbv1alpha1.LabelTenantOf] = parent.GetName()
+	child.SetLabels(l)
+}
+
+// tenantChildren returns the selector of the tenant resources of a parent.
+func tenantChildren(parent client.Object) []client.ListOption {
+	return []client.ListOption{
+		client.InNamespace(parent.GetNamespace()),
+		client.MatchingLabels{dbv1alpha1.LabelTenantOf: parent.GetName()},
+	}
+}
+
+// tenantsResult returns the result of a successful tenants reconciliation.
+// Tenants matching a pattern are discovered again after the interval.
+func tenantsResult(t *dbv1alpha1.Tenants) ctrl.Result {
+	if t.Pattern != "" {
+		return ctrl.Result{RequeueAfter: t.DiscoveryInterval()}
+	}
+	return ctrl.Result{}
+}
@@ -52,12 +52,27 @@ type (
 		down           mockCmd[atlasexec.MigrateDown]
 		lint           mockCmd[atlasexec.SummaryReport]
 		status         mockCmd[atlasexec.MigrateStatus]
+		set            mockCmd[MigrateSetParams]
+		migrateDiff    mockCmd[MigrateDiffParams]
 		schemaApply    mockCmd[atlasexec.SchemaApply]
 		whoami         mockCmd[atlasexec.WhoAmI]
 		schemaPush     mockCmd[atlasexec.SchemaPush]
 		schemaPlanList mockCmd[[]atlasexec.SchemaPlanFile]
 		schemaPlan     mockCmd[atlasexec.SchemaPlan]
 		schemaInspect  mockCmd[string]
+		schemaTest     mockCmd[string]
+		migrateTest    mockCmd[string]
+		// inspectFn overrides schemaInspect, if set.
+		inspectFn func(*atlasexec.SchemaInspectParams) (string, error)
+		// applyFn overrides schemaApply, if set.
+		applyFn func(*atlasexec.SchemaApplyParams) (*atlasexec.SchemaApply, error)
+		// diffFn is called on migrate diff, if set.
+		diffFn func(*MigrateDiffParams) error
+		// applyParams and applyCtx record the last schema apply call.
+		applyParams *atlasexec.SchemaApplyParams
+		applyCtx    *atlasexec.DeployRunContext
+		// downParams records the last migrate down call.
+		downParams *atlasexec.MigrateDownParams
 	}
 )
 
@@ -84,21 +99,45 @@ func (m *mockAtlasExec) WhoAmI(context.Context) (*atlasexec.WhoAmI, error) {
 
 // SchemaApply implements AtlasExec.
 func (m *mockAtlasExec) SchemaApply(ctx context.Context, params *atlasexec.SchemaApplyParams) (*atlasexec.SchemaApply, error) {
+	m.applyParams, m.applyCtx = params, deployContextFrom(ctx)
+	if m.applyFn != nil {
+		return m.applyFn(params)
+	}
 	return m.schemaApply.res, m.schemaApply.err
 }
 
 // SchemaInspect implements AtlasExec.
 func (m *mockAtlasExec) SchemaInspect(ctx context.Context, params *atlasexec.SchemaInspectParams) (string, error) {
+	if m.inspectFn != nil {
+		return m.inspectFn(params)
+	}
 	return *m.schemaInspect.res, m.schemaInspect.err
 }
 
+// SchemaTest implements AtlasExec.
+func (m *mockAtlasExec) SchemaTest(context.Context, *atlasexec.SchemaTestParams) (string, error) {
+	if m.schemaTest.res == nil {
+		return "", m.schemaTest.err
+	}
+	return *m.schemaTest.res, m.schemaTest.err
+}
+
+// MigrateTest implements AtlasExec.
+func (m *mockAtlasExec) MigrateTest(context.Context, *atlasexec.MigrateTestParams) (string, error) {
+	if m.migrateTest.res == nil {
+		return "", m.migrateTest.err
+	}
+	return *m.migrateTest.res, m.migrateTest.err
+}
+
 // MigrateApply implements AtlasExec.
 func (m *mockAtlasExec) MigrateApply(context.Context, *atlasexec.MigrateApplyParams) (*atlasexec.MigrateApply, error) {
 	return m.apply.res, m.apply.err
 }
 
 // MigrateDown implements AtlasExec.
-func (m *mockAtlasExec) MigrateDown(context.Context, *atlasexec.MigrateDownParams) (*atlasexec.MigrateDown, error) {
+func (m *mockAtlasExec) MigrateDown(_ context.Context, params *atlasexec.MigrateDownParams) (*atlasexec.MigrateDown, error) {
+	m.downParams = params
 	return m.down.res, m.down.err
 }
 
@@ -107,6 +146,21 @@ func (m *mockAtlasExec) MigrateLint(ctx context.Context, params *atlasexec.Migra
 	return m.lint.res, m.lint.err
 }
 
+// MigrateSet implements AtlasExec.
+func (m *mockAtlasExec) MigrateSet(_ context.Context, params *MigrateSetParams) error {
+	m.set.res = params
+	return m.set.err
+}
+
+// MigrateDiff implements AtlasExec.
+func (m *mockAtlasExec) MigrateDiff(_ context.Context, params *MigrateDiffParams) error {
+	m.migrateDiff.res = params
+	if m.diffFn != nil {
+		return m.diffFn(params)
+	}
+	return m.migrateDiff.err
+}
+
 // MigrateStatus implements AtlasExec.
 func (m *mockAtlasExec) MigrateStatus(context.Context, *atlasexec.MigrateStatusParams) (*atlasexec.MigrateStatus, error) {
 	return m.status.res, m.status.err
@@ -0,0 +1,127 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"ariga.io/atlas-go-sdk/atlasexec"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+// testsDir is the directory, relative to the working directory,
+// the test files are written to.
+const testsDir = "tests"
+
+// testsErr is returned when some of the Atlas tests fail.
+type testsErr struct {
+	failed []string
+	output string
+}
+
+func (e *testsErr) Error() string {
+	return fmt.Sprintf("tests failed: %s\n%s", strings.Join(e.failed, ", "), e.output)
+}
+
+// Reason implements the interface used for reporting events.
+func (e *testsErr) Reason() string {
+	return "TestsFailed"
+}
+
+// testFailRegex matches the names of the failing test cases in the output of
+// the `schema test` and `migrate test` commands. For example:
+//
+//	--- FAIL: users_seed (0.01s)
+var testFailRegex = regexp.MustCompile(`(?m)^\s*-+ FAIL: (\S+)`)
+
+// testFiles returns the test files defined in the ConfigMap referenced by the tests spec.
+func testFiles(ctx context.Context, r client.Reader, ns string, t *dbv1alpha1.Tests) (map[string]string, error) {
+	cm, err := getConfigMap(ctx, r, ns, &t.ConfigMapRef)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string)
+	for name, content := range cm.Data {
+		if strings.HasSuffix(name, ".hcl") {
+			files[name] = content
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("configmap %s/%s does not contain any test file (*.hcl)", ns, t.ConfigMapRef.Name)
+	}
+	return files, nil
+}
+
+// withTestFiles writes the test files to the working directory.
+func withTestFiles(files map[string]string) atlasexec.Option {
+	return func(wd *atlasexec.WorkingDir) error {
+		if len(files) == 0 {
+			return nil
+		}
+		if err := os.MkdirAll(wd.Path(testsDir), 0700); err != nil {
+			return err
+		}
+		for name, content := range files {
+			if _, err := wd.WriteFile(path.Join(testsDir, name), []byte(content)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// testSrc returns the paths of the test files to reference from the atlas.hcl.
+func testSrc(files map[string]string) []string {
+	src := make([]string, 0, len(files))
+	for name := range files {
+		src = append(src, path.Join(testsDir, name))
+	}
+	slices.Sort(src)
+	return src
+}
+
+// testsResult converts the error returned by the `schema test` and `migrate test`
+// commands. Test failures are reported with the names of the failing test cases,
+// and other errors are retried unless they are SQL errors.
+func testsResult(err error) error {
+	if err == nil {
+		return nil
+	}
+	if e := (*atlasexec.Error)(nil); errors.As(err, &e) {
+		out := strings.TrimSpace(e.Stdout + "\n" + e.Stderr)
+		var failed []string
+		for _, m := range testFailRegex.FindAllStringSubmatch(out, -1) {
+			if !slices.Contains(failed, m[1]) {
+				failed = append(failed, m[1])
+			}
+		}
+		if len(failed) > 0 {
+			return &testsErr{failed: failed, output: out}
+		}
+	}
+	if isSQLErr(err) {
+		return err
+	}
+	return transient(err)
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The Atlas Operator Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	dbv1alpha1 "github.com/ariga/atlas-operator/api/v1alpha1"
+)
+
+// lockTimeoutRegex matches the errors returned when a statement
+// waited too long for a lock:
+//
+//	PostgreSQL: canceling statement due to lock timeout (55P03)
+//	MySQL/MariaDB: Error 1205 (HY000): Lock wait timeout exceeded
+var lockTimeoutRegex = regexp.MustCompile(`(?i)due to lock timeout|lock wait timeout exceeded`)
+
+// Backoff of the retries after a lock timeout.
+const (
+	lockTimeoutBackoff    = 5 * time.Second
+	lockTimeoutMaxBackoff = 5 * time.Minute
+)
+
+// sessionURL returns the URL of the target database with the session settings of the given
+// timeouts, and the names of the timeouts that cannot be set for its driver, which are ignored.
+// The settings are passed in the URL: SQL Server sets LOCK_TIMEOUT only with a SET statement
+// in the session running the changes, and the max_execution_time of MySQL limits only SELECT
+// statements, not the DDL statements run by the operator.
+func sessionURL(u *url.URL, t *dbv1alpha1.Timeouts) (_ *url.URL, ignored []string) {
+	if t == nil || t.Lock == nil && t.Statement == nil {
+		return u, nil
+	}
+	var (
+		c = *u
+		q = c.Query()
+	)
+	switch drv := dbv1alpha1.DriverBySchema(u.Scheme); drv {
+	case dbv1alpha1.DriverPostgres:
+		// Run-time parameters are passed in the options parameter.
+		opts := strings.Fields(q.Get("options"))
+		if d := t.Lock; d != nil {
+			opts = append(opts, "-c", "lock_timeout="+strconv.FormatInt(d.Milliseconds(), 10))
+		}
+		if d := t.Statement; d != nil {
+			opts = append(opts, "-c", "statement_timeout="+strconv.FormatInt(d.Milliseconds(), 10))
+		}
+		q.Set("options", strings.Join(opts, " "))
+	case dbv1alpha1.DriverMySQL, dbv1alpha1.DriverMariaDB:
+		// Unknown parameters are set as system variables of the session.
+		if d := t.Lock; d != nil {
+			secs := strconv.FormatInt(ceilSeconds(d.Duration), 10)
+			q.Set("lock_wait_timeout", secs)
+			q.Set("innodb_lock_wait_timeout", secs)
+		}
+		if d := t.Statement; d != nil && drv == dbv1alpha1.DriverMariaDB {
+			q.Set("max_statement_time", strconv.FormatFloat(d.Seconds(), 'f', -1, 64))
+		} else if d != nil {
+			ignored = append(ignored, "statement")
+		}
+	default:
+		if t.Lock != nil {
+			ignored = append(ignored, "lock")
+		}
+		if t.Statement != nil {
+			ignored = append(ignored, "statement")
+		}
+		return u, ignored
+	}
+	c.RawQuery = q.Encode()
+	return &c, ignored
+}
+
+// ignoredTimeouts returns the warning reported when
+// some of the timeouts are not supported for the URL.
+func ignoredTimeouts(u *url.URL, t *dbv1alpha1.Timeouts) string {
+	_, ignored := sessionURL(u, t)
+	if len(ignored) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s timeouts are not supported for %q databases and are ignored", strings.Join(ignored, " and "), u.Scheme)
+}
+
+// ceilSeconds returns the duration in seconds, rounded up to at least one second.
+func ceilSeconds(d time.Duration) int64 {
+	return max(1, int64(math.Ceil(d.Seconds())))
+}
+
+// isLockTimeout reports if the error was caused by a lock timeout.
+func isLockTimeout(err error) bool {
+	return err != nil && lockTimeoutRegex.MatchString(err.Error())
+}
+
+// lockTimeoutRetry returns the error to retry after the given number of
+// consecutive lock timeouts, with an exponential backoff.
+func lockTimeoutRetry(err error, attempts int32) error {
+	after := lockTimeoutMaxBackoff
+	if attempts < 8 {
+		after = min(lockTimeoutMaxBackoff, lockTimeoutBackoff<<max(attempts-1, 0))
+	}
+	return transientAfter(err, after)
+}